@@ -6,6 +6,7 @@ import (
 	"backend/internal/discord"
 	"backend/internal/repository"
 	"backend/internal/service"
+	"backend/internal/worker"
 	"context"
 	"errors"
 	"fmt"
@@ -13,6 +14,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
@@ -34,9 +36,66 @@ func main() {
 		log.Fatalf("vrchat client init failed: %v", err)
 	}
 
+	// ========= Discord セッション準備 =========
+	// 登録に必要なロールの確認（HTTP 経由の登録も含む）で使うので、ハンドラより先に組み立てる。接続は後で。
+	discordToken := os.Getenv("DISCORD_TOKEN")
+	discordAppID := os.Getenv("DISCORD_APP_ID")
+	discordGuildID := os.Getenv("DISCORD_GUILD_ID") // dev中は Guild 指定推奨
+
+	var (
+		dSession    discord.Session
+		memberRoles service.MemberRoleLookup
+		// ホワイトリストに載っている人に付けるロール（DISCORD_WHITELISTED_ROLE_IDS）
+		roleSyncer     *discord.RoleSyncer
+		whitelistRoles service.WhitelistRoleSyncer
+		// キャンセル待ちから繰り上がった人への DM
+		waitlistNotifier service.WaitlistNotifier
+	)
+	if discordToken != "" {
+		// session.go でdiscordgo.Sessionを組み立てる
+		s, err := discord.NewSession(discordToken)
+		if err != nil {
+			log.Fatalf("failed to init discord session: %v", err)
+		}
+		dSession = s
+		memberRoles = s
+		if rs := discord.NewRoleSyncer(s); rs.Enabled() {
+			roleSyncer = rs
+			whitelistRoles = rs
+		}
+		waitlistNotifier = discord.NewWaitlistNotifier(s)
+	} else {
+		log.Println("DISCORD_TOKEN not set: discord bot disabled")
+	}
+
 	whitelistRepo := repository.NewWhitelistRepository(db)
-	whitelistService := service.NewWhitelistService(whitelistRepo, vrchat)
-	whitelistHandler := api.NewWhitelistHandler(whitelistService)
+	whitelistListRepo := repository.NewWhitelistListRepository(db)
+	whitelistAuditRepo := repository.NewWhitelistAuditRepository(db)
+	whitelistDenyRepo := repository.NewWhitelistDenyRepository(db)
+	registrationWindowRepo := repository.NewRegistrationWindowRepository(db)
+	whitelistWaitlistRepo := repository.NewWhitelistWaitlistRepository(db)
+	registerLimitRepo := repository.NewRegisterLimitRepository(db)
+	whitelistService := service.NewWhitelistService(
+		repository.NewTransactor(db),
+		whitelistRepo,
+		whitelistListRepo,
+		whitelistAuditRepo,
+		whitelistDenyRepo,
+		registrationWindowRepo,
+		whitelistWaitlistRepo,
+		registerLimitRepo,
+		vrchat,
+		whitelistRoles,
+		waitlistNotifier,
+	)
+	// 本人による登録に必要な Discord ロール（DISCORD_REQUIRED_ROLE_IDS）
+	roleGate := service.NewRoleGate(memberRoles)
+	whitelistHandler := api.NewWhitelistHandler(whitelistService, roleGate)
+
+	exportTokenRepo := repository.NewExportTokenRepository(db)
+	exportTokenService := service.NewExportTokenService(exportTokenRepo)
+	exportTokenHandler := api.NewExportTokenHandler(exportTokenService)
+	whitelistExportHandler := api.NewWhitelistExportHandler(whitelistService, exportTokenService)
 
 	healthRepo := repository.NewHealthRepository(db)
 	healthSevice := service.NewHealthService(healthRepo)
@@ -53,12 +112,16 @@ func main() {
 	e.Use(
 		middleware.Recover(),
 		middleware.RequestID(),
-		middleware.Logger(),
+		api.RequestLogger(),
 		middleware.CORS(),
 	)
 
 	// ルート設定
-	api.SetupRoutes(e, healthHandler, whitelistHandler)
+	adminAPIToken := os.Getenv("ADMIN_API_TOKEN")
+	if adminAPIToken == "" {
+		e.Logger.Warn("ADMIN_API_TOKEN not set: admin api disabled")
+	}
+	api.SetupRoutes(e, healthHandler, whitelistHandler, whitelistExportHandler, exportTokenHandler, api.AdminAuth(adminAPIToken))
 
 	// ポート設定
 	port := os.Getenv("PORT")
@@ -87,23 +150,55 @@ func main() {
 		}
 	}()
 
-	// ========= Discord セッション準備 =========
-	discordToken := os.Getenv("DISCORD_TOKEN")
-	discordAppID := os.Getenv("DISCORD_APP_ID")
-	discordGuildID := os.Getenv("DISCORD_GUILD_ID") // dev中は Guild 指定推奨
+	// ---- バックグラウンドワーカー ----
+	// シャットダウン時に workerCancel で止め、wg で終了を待ってから DB を閉じる
+	workerCtx, workerCancel := context.WithCancel(context.Background())
+	defer workerCancel()
+	var workerWG sync.WaitGroup
 
-	var dSession discord.Session
-	if discordToken != "" {
-		// session.go でdiscordgo.Sessionを組み立てる
-		s, err := discord.NewSession(discordToken)
+	// 有効期限切れのホワイトリスト登録を外す
+	expirySweeper := worker.NewExpirySweeper(whitelistService)
+	workerWG.Add(1)
+	go func() {
+		defer workerWG.Done()
+		expirySweeper.Run(workerCtx)
+	}()
+
+	// 保持期間を過ぎた論理削除済みの登録を物理削除する
+	deletedPurger := worker.NewDeletedPurger(whitelistService)
+	workerWG.Add(1)
+	go func() {
+		defer workerWG.Done()
+		deletedPurger.Run(workerCtx)
+	}()
+
+	// 定員（WHITELIST_CAPACITY）を増やして再起動したときなど、空いている枠の分だけキャンセル待ちを繰り上げる
+	workerWG.Add(1)
+	go func() {
+		defer workerWG.Done()
+		promoted, err := whitelistService.PromoteWaitlist(workerCtx)
 		if err != nil {
-			e.Logger.Fatal("failed to init discord session: ", err)
+			log.Printf("whitelist waitlist promotion failed: %v", err)
 		}
-		dSession = s
-	} else {
-		e.Logger.Warn("DISCORD_TOKEN not set: discord bot disabled")
+		if promoted > 0 {
+			log.Printf("whitelist waitlist: promoted %d entries", promoted)
+		}
+	}()
+
+	// 管理者向け通知（DISCORD_ADMIN_CHANNEL_ID が無ければログだけ）
+	var adminNotifier worker.Notifier
+	if adminChannelID := os.Getenv("DISCORD_ADMIN_CHANNEL_ID"); dSession != nil && adminChannelID != "" {
+		adminNotifier = discord.NewChannelNotifier(dSession, adminChannelID)
 	}
 
+	// VRChat の表示名・アバターを定期的に取り直す（Discord の通知先が決まってから起動する）
+	vrcResyncer := worker.NewVRCResyncer(whitelistService, adminNotifier)
+	workerWG.Add(1)
+	go func() {
+		defer workerWG.Done()
+		vrcResyncer.Run(workerCtx)
+	}()
+
 	// ---- server start & wait for signal ----
 	// サーバ起動結果（エラー）を受け取るためのチャネルを用意する（バッファ1で送信ブロックを避ける）
 	// Discord分も見たいので容量2に
@@ -120,8 +215,10 @@ func main() {
 	// Discord起動
 	if dSession != nil {
 		// DI
-		router := discord.NewRouter(whitelistService)
+		router := discord.NewRouter(whitelistService, roleGate, roleSyncer, discordGuildID)
 		dSession.AddHandler(router.HandleInteraction)
+		// サーバーを抜けた・BAN された人はホワイトリストから外す
+		dSession.AddHandler(router.HandleGuildMemberRemove)
 
 		go func() {
 			ctxStart, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -143,6 +240,18 @@ func main() {
 			}
 
 			fmt.Printf("startup complete: http=:%s, discord=online\n", port)
+
+			// Bot が止まっていた間に抜けた人を外す（見張るサーバーが1つも設定されていなければしない）
+			ctxCatchUp, cancelCatchUp := context.WithTimeout(workerCtx, 5*time.Minute)
+			defer cancelCatchUp()
+			removed, err := router.CatchUpMembers(ctxCatchUp, dSession)
+			if err != nil {
+				log.Printf("discord member catch-up failed: %v", err)
+				return
+			}
+			if removed > 0 {
+				log.Printf("discord member catch-up: removed %d whitelist entries", removed)
+			}
 		}()
 	} else {
 		fmt.Printf("startup complete: http=:%s, discord=disabled\n", port)
@@ -182,6 +291,10 @@ func main() {
 		}
 	}
 
+	// バックグラウンドワーカーを止めて終了を待つ
+	workerCancel()
+	workerWG.Wait()
+
 	// Discordを閉じる（WebSocket切断）
 	if err := dSession.Close(); err != nil {
 		e.Logger.Error("discord close:", err)
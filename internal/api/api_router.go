@@ -1,26 +1,81 @@
-package api
-
-import (
-	"github.com/labstack/echo/v4"
-)
-
-func SetupRoutes(
-	// 引数
-	e *echo.Echo,
-	healthHandler *HealthHandler,
-	whitelistHandler *WhitelistHandler) {
-
-	api := e.Group("/api")
-
-	// ヘルスチェック
-	api.GET("/livez", healthHandler.Livez)
-	api.GET("/readyz", healthHandler.Readyz)
-	api.GET("/healthz", healthHandler.Healthz)
-
-	// Discordホワイトリスト管理用
-	discord := api.Group("/discord")
-	// 登録/更新
-	discord.POST("/whitelist/register", whitelistHandler.RegisterDiscordVRC)
-	// 削除
-	discord.POST("/whitelist/remove", whitelistHandler.RemoveDiscordVRC)
-}
+package api
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+func SetupRoutes(
+	// 引数
+	e *echo.Echo,
+	healthHandler *HealthHandler,
+	whitelistHandler *WhitelistHandler,
+	whitelistExportHandler *WhitelistExportHandler,
+	exportTokenHandler *ExportTokenHandler,
+	adminAuth echo.MiddlewareFunc) {
+
+	// 変更系の service 呼び出しで監査ログに actor / request ID を残す
+	api := e.Group("/api", AuditActor())
+
+	// ヘルスチェック
+	api.GET("/livez", healthHandler.Livez)
+	api.GET("/readyz", healthHandler.Readyz)
+	api.GET("/healthz", healthHandler.Healthz)
+
+	// VRChatワールド向けホワイトリスト配信（?token= 付きなら署名付き）
+	api.GET("/whitelist/export.txt", whitelistExportHandler.ExportText)
+	api.GET("/whitelist/export.png", whitelistExportHandler.ExportPNG)
+	// 名前付きリストごと
+	api.GET("/whitelist/lists/:name/export.txt", whitelistExportHandler.ExportText)
+	api.GET("/whitelist/lists/:name/export.png", whitelistExportHandler.ExportPNG)
+
+	// Discordホワイトリスト管理用
+	discord := api.Group("/discord")
+	// 一覧・検索 / 1件取得（スタッフ向けなので管理用トークンが必要）
+	discord.GET("/whitelist", whitelistHandler.ListWhitelist, adminAuth)
+	discord.GET("/whitelist/:discord_id", whitelistHandler.GetDiscordVRC, adminAuth)
+	discord.GET("/whitelist/:discord_id/name-history", whitelistHandler.ListNameHistory, adminAuth)
+	// 登録/更新
+	discord.POST("/whitelist/register", whitelistHandler.RegisterDiscordVRC)
+	// VRChat アカウントの本人確認（bio の確認コード）
+	discord.POST("/whitelist/verify", whitelistHandler.VerifyDiscordVRC)
+	// 削除
+	discord.POST("/whitelist/remove", whitelistHandler.RemoveDiscordVRC)
+	// 名前付きリスト（作成・所属の変更は /api/admin）
+	discord.GET("/whitelist/lists", whitelistHandler.GetLists)
+
+	// 管理用（Authorization: Bearer <ADMIN_API_TOKEN>）
+	admin := api.Group("/admin", adminAuth)
+	// ワールドごとのエクスポート用トークン
+	admin.POST("/world-tokens", exportTokenHandler.Issue)
+	admin.GET("/world-tokens", exportTokenHandler.List)
+	admin.DELETE("/world-tokens/:id", exportTokenHandler.Revoke)
+	// 削除の取り消し
+	admin.POST("/whitelist/restore", whitelistHandler.RestoreDiscordVRC)
+	// 有効期限の設定/解除
+	admin.POST("/whitelist/expiry", whitelistHandler.SetExpiry)
+	// 名前付きリストの作成と所属の変更（リストごとのエクスポートの中身が変わる）
+	admin.POST("/whitelist/lists", whitelistHandler.CreateList)
+	admin.POST("/whitelist/lists/:name/add", whitelistHandler.AddToList)
+	admin.POST("/whitelist/lists/:name/remove", whitelistHandler.RemoveFromList)
+	// ホワイトリスト変更の監査ログ
+	admin.GET("/whitelist/audit", whitelistHandler.ListAudit)
+	// 管理者用メモの編集
+	admin.PATCH("/whitelist/:discord_id", whitelistHandler.UpdateNote)
+	// 登録禁止リスト
+	admin.GET("/whitelist/deny", whitelistHandler.ListDenied)
+	admin.POST("/whitelist/deny", whitelistHandler.DenyAccount)
+	admin.DELETE("/whitelist/deny/:id", whitelistHandler.RemoveDeny)
+	// 登録の受付期間と個別の許可
+	admin.GET("/whitelist/registration-window", whitelistHandler.GetRegistrationWindow)
+	admin.PUT("/whitelist/registration-window", whitelistHandler.SetRegistrationWindow)
+	admin.PUT("/whitelist/registration-overrides/:discord_id", whitelistHandler.GrantRegistrationOverride)
+	admin.DELETE("/whitelist/registration-overrides/:discord_id", whitelistHandler.RevokeRegistrationOverride)
+	// 定員とキャンセル待ち
+	admin.GET("/whitelist/waitlist", whitelistHandler.ListWaitlist)
+	// CSV 一括登録（?dry_run=true で書き込まずに結果だけ）。バックグラウンドで進むので結果はジョブを見る
+	admin.POST("/whitelist/import", whitelistHandler.ImportCSV)
+	admin.GET("/whitelist/import/:id", whitelistHandler.GetImportJob)
+	// スタッフ向けの全件エクスポート（スプレッドシート用）
+	admin.GET("/whitelist/export.csv", whitelistHandler.DumpCSV)
+	admin.GET("/whitelist/export.ndjson", whitelistHandler.DumpNDJSON)
+}
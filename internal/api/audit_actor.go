@@ -0,0 +1,24 @@
+package api
+
+import (
+	"backend/internal/service"
+
+	"github.com/labstack/echo/v4"
+)
+
+// service に渡す context に「HTTP API から・誰が・どのリクエストで」を載せる。
+// ここでは接続元IP を actor にする。管理用トークンで認証できたものは AdminAuth が上書きする。
+// middleware.RequestID より後に積むこと。
+func AuditActor() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			ctx := service.WithActor(c.Request().Context(), service.Actor{
+				ID:        c.RealIP(),
+				Source:    service.SourceHTTPAPI,
+				RequestID: c.Response().Header().Get(echo.HeaderXRequestID),
+			})
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"backend/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ワールドごとのエクスポート用トークン管理（管理者向け）
+type ExportTokenHandler struct {
+	svc service.ExportTokenService
+}
+
+func NewExportTokenHandler(s service.ExportTokenService) *ExportTokenHandler {
+	return &ExportTokenHandler{svc: s}
+}
+
+// ワールド名を受け取ってトークンと署名用シークレットを発行する。
+// token / secret を平文で返すのはこのレスポンスだけ。
+func (h *ExportTokenHandler) Issue(c echo.Context) error {
+	type IssueRequest struct {
+		WorldName string `json:"world_name"`
+	}
+
+	var r IssueRequest
+	// 400
+	if err := c.Bind(&r); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid json: "+err.Error())
+	}
+	// 400
+	if r.WorldName == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "world_name is required")
+	}
+
+	t, err := h.svc.Issue(c.Request().Context(), r.WorldName)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidArgument) {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid argument")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	// 201
+	return c.JSON(http.StatusCreated, t)
+}
+
+// 発行済みトークン一覧（失効済みも含む）
+func (h *ExportTokenHandler) List(c echo.Context) error {
+	tokens, err := h.svc.List(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	// 200
+	return c.JSON(http.StatusOK, tokens)
+}
+
+// 失効
+func (h *ExportTokenHandler) Revoke(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	// 400
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id")
+	}
+
+	if err := h.svc.Revoke(c.Request().Context(), id); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidArgument):
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid argument")
+		case errors.Is(err, service.ErrNotFound):
+			return echo.NewHTTPError(http.StatusNotFound, "token not found or already revoked")
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// アクセスログ。中身は middleware.Logger() と同じだが、uri の ?token=（ワールドトークン）は伏せる。
+// VRChat の String / Image Loader はヘッダを付けられないので、トークンはクエリで来る。
+func RequestLogger() echo.MiddlewareFunc {
+	config := middleware.DefaultLoggerConfig
+	config.Format = strings.Replace(config.Format, `"uri":"${uri}"`, `"uri":"${custom}"`, 1)
+	config.CustomTagFunc = func(c echo.Context, buf *bytes.Buffer) (int, error) {
+		return buf.WriteString(redactedURI(c))
+	}
+	return middleware.LoggerWithConfig(config)
+}
+
+func redactedURI(c echo.Context) string {
+	req := c.Request()
+	q := req.URL.Query()
+	if !q.Has("token") {
+		return req.RequestURI
+	}
+	q.Set("token", "REDACTED")
+	return req.URL.Path + "?" + q.Encode()
+}
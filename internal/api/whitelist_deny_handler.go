@@ -0,0 +1,80 @@
+package api
+
+import (
+	"backend/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+// 登録禁止リストの一覧（新しい順）
+func (h *WhitelistHandler) ListDenied(c echo.Context) error {
+	entries, err := h.svc.ListDenied(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	return c.JSON(http.StatusOK, map[string]any{"items": entries})
+}
+
+// 登録禁止リストに追加する。Discord ID と VRChat アカウントのどちらか（両方でもよい）。
+// Discord 側と VRChat 側は別の行で載り、既に載っている側は飛ばす（両方載っていれば 409）。
+// 今ある紐付けは外れる。追加した管理者は監査ログと同じ actor（管理用トークン由来の名前）が残る。
+func (h *WhitelistHandler) DenyAccount(c echo.Context) error {
+	type DenyRequest struct {
+		DiscordUserID string `json:"discord_user_id"`
+		VRCUser       string `json:"vrc_user"` // 表示名 / usr_... / プロフィールURL
+		Reason        string `json:"reason"`
+	}
+
+	var r DenyRequest
+	// 400
+	if err := c.Bind(&r); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid json: "+err.Error())
+	}
+	// 400
+	if r.DiscordUserID == "" && r.VRCUser == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "discord_user_id or vrc_user is required")
+	}
+
+	added, err := h.svc.DenyAccount(c.Request().Context(), r.DiscordUserID, r.VRCUser, r.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidArgument):
+			return echo.NewHTTPError(http.StatusBadRequest, "reason must be at most 255 characters")
+		case errors.Is(err, service.ErrNoExactMatch), errors.Is(err, service.ErrVRCUserNotFound):
+			return echo.NewHTTPError(http.StatusBadRequest, "no vrchat user found for vrc_user")
+		case errors.Is(err, service.ErrMultipleExactMatch):
+			return echo.NewHTTPError(http.StatusBadRequest, "multiple vrchat users found with same display name; use usr_ id")
+		case errors.Is(err, service.ErrAlreadyExists):
+			return echo.NewHTTPError(http.StatusConflict, "already on the deny list")
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return c.JSON(http.StatusCreated, map[string]any{"items": added})
+}
+
+// 登録禁止を解く。外れた紐付けは戻らない（必要なら restore する）。
+func (h *WhitelistHandler) RemoveDeny(c echo.Context) error {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	// 400
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid id")
+	}
+
+	if err := h.svc.RemoveDeny(c.Request().Context(), id); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidArgument):
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid argument")
+		case errors.Is(err, service.ErrNotFound):
+			return echo.NewHTTPError(http.StatusNotFound, "deny list entry not found")
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
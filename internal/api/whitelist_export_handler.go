@@ -0,0 +1,196 @@
+package api
+
+import (
+	"backend/internal/models"
+	"backend/internal/service"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// VRChat ワールド（VRCStringDownloader 等）から読まれるエクスポート。
+// ?token= にワールドトークンを付けると署名付きで返す。
+// /whitelist/lists/:name/... のときは名前付きリストの所属者だけを返す。
+type WhitelistExportHandler struct {
+	svc    service.WhitelistService
+	tokens service.ExportTokenService
+}
+
+func NewWhitelistExportHandler(s service.WhitelistService, tokens service.ExportTokenService) *WhitelistExportHandler {
+	return &WhitelistExportHandler{svc: s, tokens: tokens}
+}
+
+// 1行に1人、VRChat の表示名 or ユーザーIDを登録順で返す。
+// ?field=name（デフォルト）: vrc_display_name
+// ?field=id: vrc_user_id
+// 署名付きの形式は service.EncodeWhitelistText を参照。
+func (h *WhitelistExportHandler) ExportText(c echo.Context) error {
+	field := c.QueryParam("field")
+	if field == "" {
+		field = "name"
+	}
+	// 400
+	if field != "name" && field != "id" {
+		return echo.NewHTTPError(http.StatusBadRequest, "field must be name or id")
+	}
+
+	ctx := c.Request().Context()
+
+	sig, tag, err := h.exportSignature(c)
+	if err != nil {
+		return err
+	}
+
+	// バージョンは一覧より先に取る（逆だと古い中身に新しい ETag が付いてしまう）
+	ver, err := h.svc.GetWhitelistVersion(ctx)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	// 304
+	if notModified(c, ver, listTag(c)+"txt-"+field+tag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	users, err := h.exportUsers(c)
+	if err != nil {
+		return err
+	}
+
+	lines := make([]string, 0, len(users))
+	for _, u := range users {
+		if field == "id" {
+			lines = append(lines, u.VRCUserID)
+		} else {
+			lines = append(lines, u.VRCDisplayName)
+		}
+	}
+
+	// 200
+	return c.Blob(http.StatusOK, echo.MIMETextPlainCharsetUTF8, service.EncodeWhitelistText(lines, ver.Version, sig))
+}
+
+// VRChat Image Loader 向けに VRC user ID を詰めた PNG を返す。
+// バイトレイアウトは service.EncodeWhitelistPNG を参照。
+func (h *WhitelistExportHandler) ExportPNG(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	sig, tag, err := h.exportSignature(c)
+	if err != nil {
+		return err
+	}
+
+	ver, err := h.svc.GetWhitelistVersion(ctx)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	// 304
+	if notModified(c, ver, listTag(c)+"png"+tag) {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	users, err := h.exportUsers(c)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]string, 0, len(users))
+	for _, u := range users {
+		ids = append(ids, u.VRCUserID)
+	}
+
+	b, err := service.EncodeWhitelistPNG(uint32(ver.Version), ids, sig)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	// 200
+	return c.Blob(http.StatusOK, "image/png", b)
+}
+
+// :name があればそのリストの所属者、無ければ全件
+func (h *WhitelistExportHandler) exportUsers(c echo.Context) ([]models.WhitelistUser, error) {
+	ctx := c.Request().Context()
+
+	name := c.Param("name")
+	if name == "" {
+		users, err := h.svc.ListWhitelist(ctx)
+		if err != nil {
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+		return users, nil
+	}
+
+	users, err := h.svc.ListWhitelistByList(ctx, name)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidArgument):
+			return nil, echo.NewHTTPError(http.StatusBadRequest, "invalid list name")
+		case errors.Is(err, service.ErrListNotFound):
+			return nil, echo.NewHTTPError(http.StatusNotFound, "list not found")
+		default:
+			return nil, echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+	return users, nil
+}
+
+// ETag をリストごとに分けるためのタグ
+func listTag(c echo.Context) string {
+	if name := c.Param("name"); name != "" {
+		return "l-" + name + "-"
+	}
+	return ""
+}
+
+// ?token= からワールドトークンを照合して署名を用意する。
+// トークン無しなら (nil, "", nil)。ETag をワールドごとに分けるためのタグも返す。
+func (h *WhitelistExportHandler) exportSignature(c echo.Context) (*service.ExportSignature, string, error) {
+	token := c.QueryParam("token")
+	if token == "" {
+		// 401
+		if h.tokens.TokenRequired() {
+			return nil, "", echo.NewHTTPError(http.StatusUnauthorized, "token is required")
+		}
+		return nil, "", nil
+	}
+
+	t, err := h.tokens.Authenticate(c.Request().Context(), token)
+	if err != nil {
+		// 401
+		if errors.Is(err, service.ErrInvalidToken) {
+			return nil, "", echo.NewHTTPError(http.StatusUnauthorized, "invalid token")
+		}
+		return nil, "", echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return service.NewExportSignature(t.Secret), fmt.Sprintf("-w%d", t.ID), nil
+}
+
+// ETag / Last-Modified を付け、If-None-Match が今の ETag と一致すれば true を返す。
+// variant は同じバージョンでも表現ごとに ETag を分けるためのもの（txt-name, png など）。
+func notModified(c echo.Context, ver *models.WhitelistVersion, variant string) bool {
+	etag := fmt.Sprintf(`"wl-%d-%s"`, ver.Version, variant)
+
+	res := c.Response().Header()
+	res.Set(echo.HeaderCacheControl, "no-cache")
+	res.Set("ETag", etag)
+	if !ver.UpdatedAt.IsZero() {
+		res.Set(echo.HeaderLastModified, ver.UpdatedAt.UTC().Format(http.TimeFormat))
+	}
+
+	inm := c.Request().Header.Get("If-None-Match")
+	if inm == "" {
+		return false
+	}
+	for _, t := range strings.Split(inm, ",") {
+		t = strings.TrimSpace(t)
+		// 弱い比較でよいので W/ は外して比べる
+		t = strings.TrimPrefix(t, "W/")
+		if t == "*" || t == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -1,97 +1,592 @@
-package api
-
-import (
-	"backend/internal/service"
-	"errors"
-	"net/http"
-
-	"github.com/labstack/echo/v4"
-)
-
-type WhitelistHandler struct {
-	svc service.WhitelistService
-}
-
-func NewWhitelistHandler(s service.WhitelistService) *WhitelistHandler {
-	return &WhitelistHandler{svc: s}
-}
-
-// Discord ID と VRC displayName を受け取り、
-// VRChat APIで完全一致ユーザーを検索して whitelist_users に登録/更新する。
-func (h *WhitelistHandler) RegisterDiscordVRC(c echo.Context) error {
-	type RegisterDiscordVRCRequest struct {
-		DiscordUserID  string `json:"discord_user_id"`
-		VRCDisplayName string `json:"vrc_display_name"`
-	}
-
-	var r RegisterDiscordVRCRequest
-	// 400
-	if err := c.Bind(&r); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid json: "+err.Error())
-	}
-	// 400
-	if r.DiscordUserID == "" || r.VRCDisplayName == "" {
-		return echo.NewHTTPError(http.StatusBadRequest, "discord_user_id and vrc_display_name are required")
-	}
-
-	created, err := h.svc.RegisterDiscordVRC(
-		c.Request().Context(),
-		r.DiscordUserID,
-		r.VRCDisplayName,
-	)
-	if err != nil {
-		switch {
-		case errors.Is(err, service.ErrInvalidArgument):
-			return echo.NewHTTPError(http.StatusBadRequest, "invalid argument")
-		case errors.Is(err, service.ErrNoExactMatch):
-			// VRChat Search All Users に完全一致が無かった
-			return echo.NewHTTPError(http.StatusBadRequest, "no exact-matched vrchat user found for given display name")
-		case errors.Is(err, service.ErrMultipleExactMatch):
-			// 同じdisplayNameのユーザーが複数いて特定できない
-			return echo.NewHTTPError(http.StatusBadRequest, "multiple vrchat users found with same display name")
-		case errors.Is(err, service.ErrAlreadyExists):
-			// その VRC userId は別のDiscordユーザーに既に紐づいている
-			return echo.NewHTTPError(http.StatusConflict, "vrchat account already linked to another discord user")
-		default:
-			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
-		}
-	}
-
-	status := http.StatusOK
-	if created {
-		status = http.StatusCreated
-	}
-
-	// 必要に応じて情報返したいならここで JSON 返す
-	// （今は最低限フラグだけ）
-	return c.JSON(status, map[string]any{
-		"created": created,
-	})
-}
-
-// 指定Discordユーザーの紐付け解除
-func (h *WhitelistHandler) RemoveDiscordVRC(c echo.Context) error {
-	type RemoveDiscordVRCRequest struct {
-		DiscordUserID string `json:"discord_user_id"`
-	}
-
-	var r RemoveDiscordVRCRequest
-	if err := c.Bind(&r); err != nil {
-		return echo.NewHTTPError(http.StatusBadRequest, "invalid json: "+err.Error())
-	}
-	if r.DiscordUserID == "" {
-		return echo.NewHTTPError(http.StatusBadRequest, "discord_user_id is required")
-	}
-
-	if err := h.svc.RemoveDiscord(
-		c.Request().Context(),
-		r.DiscordUserID,
-	); err != nil {
-		if errors.Is(err, service.ErrInvalidArgument) {
-			return echo.NewHTTPError(http.StatusBadRequest, "invalid argument")
-		}
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
-	}
-
-	return c.NoContent(http.StatusNoContent)
-}
+package api
+
+import (
+	"backend/internal/models"
+	"backend/internal/service"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type WhitelistHandler struct {
+	svc     service.WhitelistService
+	roles   service.RoleGate
+	imports *importJobs
+}
+
+func NewWhitelistHandler(s service.WhitelistService, roles service.RoleGate) *WhitelistHandler {
+	return &WhitelistHandler{svc: s, roles: roles, imports: newImportJobs()}
+}
+
+// Discord ID と VRC displayName（または usr_ ID / プロフィールURL）を受け取り、
+// VRChat APIでユーザーを特定して whitelist_users に登録/更新する。
+func (h *WhitelistHandler) RegisterDiscordVRC(c echo.Context) error {
+	type RegisterDiscordVRCRequest struct {
+		DiscordUserID  string `json:"discord_user_id"`
+		VRCDisplayName string `json:"vrc_display_name"`
+		VRCUserID      string `json:"vrc_user_id"` // usr_... かプロフィールURL。あれば表示名より優先
+	}
+
+	var r RegisterDiscordVRCRequest
+	// 400
+	if err := c.Bind(&r); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid json: "+err.Error())
+	}
+	vrcUser := r.VRCDisplayName
+	if r.VRCUserID != "" {
+		vrcUser = r.VRCUserID
+	}
+	// 400
+	if r.DiscordUserID == "" || vrcUser == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "discord_user_id and vrc_display_name or vrc_user_id are required")
+	}
+
+	// Discord の /whitelist パネルと同じく、必要なロールを持っている人だけ登録できる
+	if err := h.roles.CheckDiscordUser(c.Request().Context(), r.DiscordUserID); err != nil {
+		switch {
+		case errors.Is(err, service.ErrMissingRole):
+			return echo.NewHTTPError(http.StatusForbidden, "discord user does not have a role required to register")
+		case errors.Is(err, service.ErrRoleCheckUnavailable):
+			return echo.NewHTTPError(http.StatusServiceUnavailable, "discord bot is disabled; cannot check required roles")
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	created, err := h.svc.RegisterDiscordVRC(
+		c.Request().Context(),
+		r.DiscordUserID,
+		vrcUser,
+	)
+	// 定員に達していたのでキャンセル待ちに並んだ（空きが出たら自動で登録される）
+	var waitlisted *service.WaitlistedError
+	if errors.As(err, &waitlisted) {
+		return c.JSON(http.StatusAccepted, map[string]any{
+			"created":    false,
+			"waitlisted": true,
+			"position":   waitlisted.Position,
+		})
+	}
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidArgument):
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid argument")
+		case errors.Is(err, service.ErrNoExactMatch):
+			// VRChat Search All Users に完全一致が無かった
+			return echo.NewHTTPError(http.StatusBadRequest, "no exact-matched vrchat user found for given display name")
+		case errors.Is(err, service.ErrMultipleExactMatch):
+			// 同じdisplayNameのユーザーが複数いて特定できない
+			return echo.NewHTTPError(http.StatusBadRequest, "multiple vrchat users found with same display name")
+		case errors.Is(err, service.ErrVRCUserNotFound):
+			// usr_ ID / URL で指定したユーザーが存在しない
+			return echo.NewHTTPError(http.StatusBadRequest, "no vrchat user found for given user id")
+		case errors.Is(err, service.ErrAlreadyExists):
+			// その VRC userId は別のDiscordユーザーに既に紐づいている
+			return echo.NewHTTPError(http.StatusConflict, "vrchat account already linked to another discord user")
+		case errors.Is(err, service.ErrDenied):
+			// Discord ID か VRC userId が登録禁止リストに載っている
+			return echo.NewHTTPError(http.StatusForbidden, "discord or vrchat account is on the deny list")
+		case errors.Is(err, service.ErrRegistrationClosed):
+			// 受付期間外で、個別の許可も無い
+			return echo.NewHTTPError(http.StatusForbidden, "registration is closed")
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+
+	res := map[string]any{
+		"created":  created,
+		"verified": true,
+	}
+	// 本人確認待ちなら、bio に書いてもらうコードと期限も返す（確認は /whitelist/verify）
+	u, err := h.svc.GetDiscordVRC(c.Request().Context(), r.DiscordUserID)
+	if err == nil && u != nil && u.VerifiedAt == nil {
+		res["verified"] = false
+		res["verify_code"] = u.VerifyCode
+		res["verify_until"] = u.VerifyUntil
+	}
+	return c.JSON(status, res)
+}
+
+// VRChat の bio に確認コードが書かれているか確認して、本人確認を済ませる
+func (h *WhitelistHandler) VerifyDiscordVRC(c echo.Context) error {
+	type VerifyRequest struct {
+		DiscordUserID string `json:"discord_user_id"`
+	}
+
+	var r VerifyRequest
+	// 400
+	if err := c.Bind(&r); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid json: "+err.Error())
+	}
+	// 400
+	if r.DiscordUserID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "discord_user_id is required")
+	}
+
+	if err := h.svc.VerifyDiscordVRC(c.Request().Context(), r.DiscordUserID); err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotRegistered):
+			return echo.NewHTTPError(http.StatusNotFound, "discord user is not registered")
+		case errors.Is(err, service.ErrVerifyCodeNotFound):
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, "verify code not found in vrchat bio")
+		case errors.Is(err, service.ErrVerifyExpired):
+			return echo.NewHTTPError(http.StatusGone, "verify code expired; register again")
+		case errors.Is(err, service.ErrVRCUserNotFound):
+			return echo.NewHTTPError(http.StatusUnprocessableEntity, "linked vrchat user no longer exists")
+		case errors.Is(err, service.ErrAlreadyExists):
+			// 同じ VRChat アカウントを他の人が先に確認した
+			return echo.NewHTTPError(http.StatusConflict, "vrchat account already verified by another discord user")
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// 指定Discordユーザーの紐付け解除
+func (h *WhitelistHandler) RemoveDiscordVRC(c echo.Context) error {
+	type RemoveDiscordVRCRequest struct {
+		DiscordUserID string `json:"discord_user_id"`
+	}
+
+	var r RemoveDiscordVRCRequest
+	if err := c.Bind(&r); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid json: "+err.Error())
+	}
+	if r.DiscordUserID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "discord_user_id is required")
+	}
+
+	if err := h.svc.RemoveDiscord(
+		c.Request().Context(),
+		r.DiscordUserID,
+	); err != nil {
+		if errors.Is(err, service.ErrInvalidArgument) {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid argument")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// 名前付きリストの一覧
+func (h *WhitelistHandler) GetLists(c echo.Context) error {
+	lists, err := h.svc.GetLists(c.Request().Context())
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	// 200
+	return c.JSON(http.StatusOK, lists)
+}
+
+// 名前付きリストの作成
+func (h *WhitelistHandler) CreateList(c echo.Context) error {
+	type CreateListRequest struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		SelfService bool   `json:"self_service"`
+	}
+
+	var r CreateListRequest
+	// 400
+	if err := c.Bind(&r); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid json: "+err.Error())
+	}
+	// 400
+	if r.Name == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "name is required")
+	}
+
+	list, err := h.svc.CreateList(c.Request().Context(), r.Name, r.Description, r.SelfService)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidArgument):
+			return echo.NewHTTPError(http.StatusBadRequest, "name must match [a-z0-9][a-z0-9_-]{0,63}")
+		case errors.Is(err, service.ErrAlreadyExists):
+			return echo.NewHTTPError(http.StatusConflict, "list already exists")
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	// 201
+	return c.JSON(http.StatusCreated, list)
+}
+
+// 指定Discordユーザーの紐付けをリストに追加
+func (h *WhitelistHandler) AddToList(c echo.Context) error {
+	return h.changeListMembership(c, h.svc.AddToList, "added")
+}
+
+// 指定Discordユーザーの紐付けをリストから外す
+func (h *WhitelistHandler) RemoveFromList(c echo.Context) error {
+	return h.changeListMembership(c, h.svc.RemoveFromList, "removed")
+}
+
+// AddToList / RemoveFromList 共通
+func (h *WhitelistHandler) changeListMembership(
+	c echo.Context,
+	op func(ctx context.Context, listName, discordID string) (bool, error),
+	resultKey string,
+) error {
+	type ListMembershipRequest struct {
+		DiscordUserID string `json:"discord_user_id"`
+	}
+
+	var r ListMembershipRequest
+	// 400
+	if err := c.Bind(&r); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid json: "+err.Error())
+	}
+	// 400
+	if r.DiscordUserID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "discord_user_id is required")
+	}
+
+	changed, err := op(c.Request().Context(), c.Param("name"), r.DiscordUserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidArgument):
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid argument")
+		case errors.Is(err, service.ErrListNotFound):
+			return echo.NewHTTPError(http.StatusNotFound, "list not found")
+		case errors.Is(err, service.ErrNotRegistered):
+			// まだ VRChat アカウントを紐づけていない
+			return echo.NewHTTPError(http.StatusNotFound, "discord user is not registered")
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	// 200
+	return c.JSON(http.StatusOK, map[string]any{
+		resultKey: changed,
+	})
+}
+
+// 指定Discordユーザーの有効期限を設定する。expires_at が null なら無期限に戻す。
+func (h *WhitelistHandler) SetExpiry(c echo.Context) error {
+	type SetExpiryRequest struct {
+		DiscordUserID string     `json:"discord_user_id"`
+		ExpiresAt     *time.Time `json:"expires_at"` // RFC3339
+	}
+
+	var r SetExpiryRequest
+	// 400
+	if err := c.Bind(&r); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid json: "+err.Error())
+	}
+	// 400
+	if r.DiscordUserID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "discord_user_id is required")
+	}
+
+	if err := h.svc.SetExpiry(c.Request().Context(), r.DiscordUserID, r.ExpiresAt); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidArgument):
+			return echo.NewHTTPError(http.StatusBadRequest, "expires_at must be in the future")
+		case errors.Is(err, service.ErrNotRegistered):
+			return echo.NewHTTPError(http.StatusNotFound, "discord user is not registered")
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// 管理者用メモを書き換える。空文字で消す。更新後の紐付けを返す。
+func (h *WhitelistHandler) UpdateNote(c echo.Context) error {
+	type UpdateNoteRequest struct {
+		Note *string `json:"note"`
+	}
+
+	discordID := c.Param("discord_id")
+	var r UpdateNoteRequest
+	// 400
+	if err := c.Bind(&r); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid json: "+err.Error())
+	}
+	// 400
+	if r.Note == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "note is required")
+	}
+
+	ctx := c.Request().Context()
+	if err := h.svc.SetNote(ctx, discordID, *r.Note); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidArgument):
+			return echo.NewHTTPError(http.StatusBadRequest, "note must be at most 255 characters")
+		case errors.Is(err, service.ErrNotRegistered):
+			return echo.NewHTTPError(http.StatusNotFound, "discord user is not registered")
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	u, err := h.svc.GetDiscordVRC(ctx, discordID)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	if u == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "discord user is not registered")
+	}
+	return c.JSON(http.StatusOK, u)
+}
+
+// 削除した紐付けを元に戻す。保持期間を過ぎて物理削除された後は戻せない。
+func (h *WhitelistHandler) RestoreDiscordVRC(c echo.Context) error {
+	type RestoreRequest struct {
+		DiscordUserID string `json:"discord_user_id"`
+	}
+
+	var r RestoreRequest
+	// 400
+	if err := c.Bind(&r); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid json: "+err.Error())
+	}
+	// 400
+	if r.DiscordUserID == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "discord_user_id is required")
+	}
+
+	if err := h.svc.RestoreDiscord(c.Request().Context(), r.DiscordUserID); err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotFound):
+			return echo.NewHTTPError(http.StatusNotFound, "no deleted whitelist entry for this discord user")
+		case errors.Is(err, service.ErrAlreadyExists):
+			return echo.NewHTTPError(http.StatusConflict, "vrchat account already linked to another discord user")
+		case errors.Is(err, service.ErrDenied):
+			return echo.NewHTTPError(http.StatusForbidden, "discord or vrchat account is on the deny list")
+		default:
+			return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		}
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
+
+// 登録の一覧・検索（スタッフ向け）。作成日時 or 更新日時の新しい順。
+// ?discord_user_id= / ?vrc_user_id= は完全一致、?vrc_display_name= は部分一致で絞り込む。
+// ?sort=created|updated、?limit=、?cursor= に前ページの next_cursor を渡すと続きを取れる。
+func (h *WhitelistHandler) ListWhitelist(c echo.Context) error {
+	f := models.WhitelistUserFilter{
+		DiscordUserID:  c.QueryParam("discord_user_id"),
+		VRCUserID:      c.QueryParam("vrc_user_id"),
+		VRCDisplayName: c.QueryParam("vrc_display_name"),
+		Sort:           c.QueryParam("sort"),
+		Cursor:         c.QueryParam("cursor"),
+	}
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		// 400
+		if err != nil || n <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid limit")
+		}
+		f.Limit = n
+	}
+
+	page, err := h.svc.SearchWhitelist(c.Request().Context(), f)
+	if err != nil {
+		// 400
+		if errors.Is(err, service.ErrInvalidArgument) {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid sort or cursor")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	// 200
+	return c.JSON(http.StatusOK, page)
+}
+
+// Discord ID 1件分の登録を返す。未登録なら 404。
+func (h *WhitelistHandler) GetDiscordVRC(c echo.Context) error {
+	u, err := h.svc.GetDiscordVRC(c.Request().Context(), c.Param("discord_id"))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidArgument) {
+			return echo.NewHTTPError(http.StatusBadRequest, "discord_id is required")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	// 404
+	if u == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "discord user is not registered")
+	}
+
+	// 200
+	return c.JSON(http.StatusOK, u)
+}
+
+// VRChat 表示名の変更履歴を新しい順に返す
+func (h *WhitelistHandler) ListNameHistory(c echo.Context) error {
+	changes, err := h.svc.ListNameHistory(c.Request().Context(), c.Param("discord_id"))
+	if err != nil {
+		// 400
+		if errors.Is(err, service.ErrInvalidArgument) {
+			return echo.NewHTTPError(http.StatusBadRequest, "discord_id is required")
+		}
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	// 200
+	return c.JSON(http.StatusOK, map[string]any{
+		"items": changes,
+	})
+}
+
+// インポートで受け付ける CSV の最大サイズ（Discord の添付と同じ）
+const maxImportBodySize = 1 << 20
+
+// CSV で一括登録する（管理者向け）。本文に CSV をそのまま送るか、multipart の file フィールドで送る。
+// ?dry_run=true なら書き込まずに行ごとの結果だけ返す。
+// VRChat API を1行ずつ叩くのでバックグラウンドで進め、すぐにジョブを返す（結果は GetImportJob で取る）。
+func (h *WhitelistHandler) ImportCSV(c echo.Context) error {
+	dryRun := false
+	if v := c.QueryParam("dry_run"); v != "" {
+		b, err := strconv.ParseBool(v)
+		// 400
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid dry_run")
+		}
+		dryRun = b
+	}
+
+	var body io.Reader = c.Request().Body
+	if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		fh, err := c.FormFile("file")
+		// 400
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "file is required")
+		}
+		f, err := fh.Open()
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid file: "+err.Error())
+		}
+		defer f.Close()
+		body = f
+	}
+	// ジョブはリクエストが終わってから読むので、先に全部読んでおく
+	csvBody, err := io.ReadAll(io.LimitReader(body, maxImportBodySize+1))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid body: "+err.Error())
+	}
+	// 413
+	if len(csvBody) > maxImportBodySize {
+		return echo.NewHTTPError(http.StatusRequestEntityTooLarge, "csv is too large (up to 1MB)")
+	}
+
+	job, err := h.imports.start(dryRun)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	// リクエストが終わっても止めない。actor / request ID は引き継ぐ
+	ctx := context.WithoutCancel(c.Request().Context())
+	go func() {
+		report, err := h.svc.ImportWhitelistCSV(ctx, bytes.NewReader(csvBody), dryRun)
+		switch {
+		case errors.Is(err, service.ErrInvalidArgument):
+			h.imports.finish(job.ID, nil, "invalid csv: header needs discord and vrchat columns, up to 500 rows")
+		case err != nil:
+			log.Printf("whitelist import job %s failed: %+v", job.ID, err)
+			h.imports.finish(job.ID, nil, "internal error")
+		default:
+			h.imports.finish(job.ID, report, "")
+		}
+	}()
+
+	// 202
+	return c.JSON(http.StatusAccepted, job)
+}
+
+// CSV 一括インポートのジョブの状態と、終わっていれば結果を返す
+func (h *WhitelistHandler) GetImportJob(c echo.Context) error {
+	job, ok := h.imports.get(c.Param("id"))
+	// 404
+	if !ok {
+		return echo.NewHTTPError(http.StatusNotFound, "import job not found")
+	}
+	// 200
+	return c.JSON(http.StatusOK, job)
+}
+
+// 全件を CSV で返す（スタッフのスプレッドシート向け）
+func (h *WhitelistHandler) DumpCSV(c echo.Context) error {
+	return h.dump(c, service.DumpFormatCSV, "text/csv; charset=UTF-8")
+}
+
+// 全件を1行1件の JSON（NDJSON）で返す
+func (h *WhitelistHandler) DumpNDJSON(c echo.Context) error {
+	return h.dump(c, service.DumpFormatNDJSON, "application/x-ndjson")
+}
+
+// 全件エクスポートの書き出しにかけてよい時間（サーバー全体の WriteTimeout 15秒では件数が多いと切れる）
+const dumpWriteTimeout = 10 * time.Minute
+
+// DB から読んだ順にそのままレスポンスへ書き出す。
+// 書き始めた後はステータスを変えられないので、途中で失敗したらログに残して打ち切る。
+func (h *WhitelistHandler) dump(c echo.Context, format, contentType string) error {
+	filename := "whitelist-" + time.Now().Format("20060102-150405") + "." + format
+
+	res := c.Response()
+	// このレスポンスだけ書き込みの期限を延ばす
+	if err := http.NewResponseController(res).SetWriteDeadline(time.Now().Add(dumpWriteTimeout)); err != nil {
+		log.Printf("whitelist dump (%s): cannot extend write deadline: %+v", format, err)
+	}
+	res.Header().Set(echo.HeaderContentType, contentType)
+	res.Header().Set(echo.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+	res.WriteHeader(http.StatusOK)
+
+	if err := h.svc.DumpWhitelist(c.Request().Context(), res, format); err != nil {
+		log.Printf("whitelist dump (%s) aborted: %+v", format, err)
+	}
+	return nil
+}
+
+// 監査ログを新しい順に返す（管理者向け）。
+// ?discord_user_id= で対象を絞り込み、?before_id= に前ページの next_before_id を渡すと続きを取れる。
+func (h *WhitelistHandler) ListAudit(c echo.Context) error {
+	f := models.WhitelistAuditFilter{
+		DiscordUserID: c.QueryParam("discord_user_id"),
+	}
+	if v := c.QueryParam("before_id"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		// 400
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid before_id")
+		}
+		f.BeforeID = id
+	}
+	if v := c.QueryParam("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		// 400
+		if err != nil || n <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid limit")
+		}
+		f.Limit = n
+	}
+
+	page, err := h.svc.ListAudit(c.Request().Context(), f)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	// 200（続きが無ければ next_before_id は 0）
+	return c.JSON(http.StatusOK, page)
+}
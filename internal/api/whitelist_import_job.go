@@ -0,0 +1,84 @@
+package api
+
+import (
+	"backend/internal/models"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// 終わったインポートのジョブを結果を取りに来られるように残しておく時間
+const importJobTTL = time.Hour
+
+// CSV 一括インポートのジョブ置き場。VRChat API を1行ずつ叩くので、リクエストの中では終わらない（WriteTimeout 15秒）。
+// メモリ上にだけ持つので、サーバーを再起動すると消える。
+type importJobs struct {
+	mu   sync.Mutex
+	jobs map[string]*models.WhitelistImportJob
+}
+
+func newImportJobs() *importJobs {
+	return &importJobs{jobs: map[string]*models.WhitelistImportJob{}}
+}
+
+// 実行中のジョブを作って、そのコピーを返す
+func (j *importJobs) start(dryRun bool) (models.WhitelistImportJob, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return models.WhitelistImportJob{}, err
+	}
+	job := &models.WhitelistImportJob{
+		ID:        hex.EncodeToString(b),
+		Status:    models.ImportJobRunning,
+		DryRun:    dryRun,
+		StartedAt: time.Now(),
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.sweepLocked(job.StartedAt)
+	j.jobs[job.ID] = job
+	return *job, nil
+}
+
+// 結果を書き込む。errMsg が空でなければ失敗扱い
+func (j *importJobs) finish(id string, report *models.WhitelistImportReport, errMsg string) {
+	now := time.Now()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	job, ok := j.jobs[id]
+	if !ok {
+		return
+	}
+	job.FinishedAt = &now
+	if errMsg != "" {
+		job.Status = models.ImportJobFailed
+		job.Error = errMsg
+		return
+	}
+	job.Status = models.ImportJobDone
+	job.Report = report
+}
+
+// 無ければ false
+func (j *importJobs) get(id string) (models.WhitelistImportJob, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.sweepLocked(time.Now())
+	job, ok := j.jobs[id]
+	if !ok {
+		return models.WhitelistImportJob{}, false
+	}
+	return *job, true
+}
+
+// 終わってから importJobTTL 過ぎたジョブを捨てる
+func (j *importJobs) sweepLocked(now time.Time) {
+	for id, job := range j.jobs {
+		if job.FinishedAt != nil && now.Sub(*job.FinishedAt) > importJobTTL {
+			delete(j.jobs, id)
+		}
+	}
+}
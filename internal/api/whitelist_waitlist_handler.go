@@ -0,0 +1,26 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// 定員の埋まり具合と、キャンセル待ちの列（並んだ順）
+func (h *WhitelistHandler) ListWaitlist(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	capacity, err := h.svc.GetCapacity(ctx)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+	entries, err := h.svc.ListWaitlist(ctx)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"capacity": capacity,
+		"items":    entries,
+	})
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"backend/internal/models"
+	"backend/internal/service"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// 受付期間と、個別に許可されている人。?list= でリストの参加受付期間。
+func (h *WhitelistHandler) GetRegistrationWindow(c echo.Context) error {
+	ctx := c.Request().Context()
+	listName := c.QueryParam("list")
+
+	w, err := h.svc.GetRegistrationWindow(ctx, listName)
+	if err != nil {
+		return registrationWindowError(err)
+	}
+	overrides, err := h.svc.ListRegistrationOverrides(ctx)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"list":      listName,
+		"window":    w,
+		"open":      w.IsOpen(time.Now()),
+		"overrides": overrides,
+	})
+}
+
+// 受付期間を置き換える。opens_at / closes_at は RFC3339、null（省略）の側は制限なし。
+// list を省略するとホワイトリスト全体（本人による登録）の受付期間。
+func (h *WhitelistHandler) SetRegistrationWindow(c echo.Context) error {
+	type SetRegistrationWindowRequest struct {
+		List     string     `json:"list"`
+		OpensAt  *time.Time `json:"opens_at"`
+		ClosesAt *time.Time `json:"closes_at"`
+	}
+
+	var r SetRegistrationWindowRequest
+	// 400
+	if err := c.Bind(&r); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid json: "+err.Error())
+	}
+
+	w := models.RegistrationWindow{OpensAt: r.OpensAt, ClosesAt: r.ClosesAt}
+	if err := h.svc.SetRegistrationWindow(c.Request().Context(), r.List, w); err != nil {
+		return registrationWindowError(err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"list":   r.List,
+		"window": w,
+		"open":   w.IsOpen(time.Now()),
+	})
+}
+
+// 受付期間の外でも登録・リスト参加できるようにする
+func (h *WhitelistHandler) GrantRegistrationOverride(c echo.Context) error {
+	granted, err := h.svc.GrantRegistrationOverride(c.Request().Context(), c.Param("discord_id"))
+	if err != nil {
+		return registrationOverrideError(err)
+	}
+	return c.JSON(http.StatusOK, map[string]any{"granted": granted})
+}
+
+// 個別の許可を取り消す。許可されていなければ 404。
+func (h *WhitelistHandler) RevokeRegistrationOverride(c echo.Context) error {
+	revoked, err := h.svc.RevokeRegistrationOverride(c.Request().Context(), c.Param("discord_id"))
+	if err != nil {
+		return registrationOverrideError(err)
+	}
+	if !revoked {
+		return echo.NewHTTPError(http.StatusNotFound, "no registration override for this discord user")
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func registrationWindowError(err error) error {
+	switch {
+	case errors.Is(err, service.ErrInvalidArgument):
+		return echo.NewHTTPError(http.StatusBadRequest, "opens_at must be before closes_at")
+	case errors.Is(err, service.ErrListNotFound):
+		return echo.NewHTTPError(http.StatusNotFound, "list not found")
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+}
+
+func registrationOverrideError(err error) error {
+	switch {
+	// 400
+	case errors.Is(err, service.ErrInvalidArgument):
+		return echo.NewHTTPError(http.StatusBadRequest, "discord_id is required")
+	default:
+		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+	}
+}
@@ -1,40 +1,335 @@
-package discord
-
-import "github.com/bwmarrin/discordgo"
-
-// CommandName は Slash Command 名の型
-type CommandName string
-
-// コマンド名一覧（ここだけ見ればOK）
-const (
-	CommandPing      CommandName = "ping"
-	CommandWhitelist CommandName = "whitelist"
-	// CommandTournament CommandName = "tournament"
-	// CommandCypher     CommandName = "cypher"
-	// CommandBeat       CommandName = "beat"
-)
-
-// CommandDef は 1コマンド分の定義
-type CommandDef struct {
-	Name        CommandName
-	Description string
-	Options     []*discordgo.ApplicationCommandOption
-}
-
-// Commands は登録対象のコマンド一覧
-// → ApplicationCommandCreate 時にも、ハンドラ側の分岐にもこれを使う。
-var Commands = []CommandDef{
-	{
-		Name:        CommandPing,
-		Description: "Botの疎通確認を行う。",
-	},
-	{
-		Name:        CommandWhitelist,
-		Description: "自分のホワイトリスト状態を確認・編集する。",
-	},
-	// 将来的な拡張:
-	// {
-	// 	Name:        CommandTournament,
-	// 	Description: "大会関連の操作を行う。",
-	// },
-}
+package discord
+
+import "github.com/bwmarrin/discordgo"
+
+// CommandName は Slash Command 名の型
+type CommandName string
+
+// コマンド名一覧（ここだけ見ればOK）
+const (
+	CommandPing           CommandName = "ping"
+	CommandWhitelist      CommandName = "whitelist"
+	CommandWhitelistAdmin CommandName = "whitelist-admin"
+	// CommandTournament CommandName = "tournament"
+	// CommandCypher     CommandName = "cypher"
+	// CommandBeat       CommandName = "beat"
+)
+
+// 管理者向けサブコマンド名（/whitelist-admin <sub>）
+const (
+	SubcommandWhitelistAdd       = "add"
+	SubcommandWhitelistRemove    = "remove"
+	SubcommandWhitelistShow      = "show"
+	SubcommandWhitelistLookup    = "lookup"
+	SubcommandWhitelistNote      = "note"
+	SubcommandWhitelistExpire    = "expire"
+	SubcommandWhitelistAudit     = "audit"
+	SubcommandWhitelistRestore   = "restore"
+	SubcommandWhitelistImport    = "import"
+	SubcommandWhitelistExport    = "export"
+	SubcommandWhitelistReconcile = "reconcile"
+	SubcommandWhitelistDeny      = "deny"
+	SubcommandWhitelistUndeny    = "undeny"
+	SubcommandWhitelistDenylist  = "denylist"
+	SubcommandWhitelistWindow    = "window"
+	SubcommandWhitelistOverride  = "window-override"
+	SubcommandWhitelistWaitlist  = "waitlist"
+)
+
+// CommandDef は 1コマンド分の定義
+type CommandDef struct {
+	Name        CommandName
+	Description string
+	Options     []*discordgo.ApplicationCommandOption
+	// nil なら全員が使える。管理者向けコマンドは adminPermissions を入れる
+	DefaultMemberPermissions *int64
+}
+
+// 管理者向けコマンドを既定で見せる権限（サーバー管理）
+var adminPermissions = int64(discordgo.PermissionManageGuild)
+
+// /whitelist-admin audit の表示件数
+var (
+	auditLimitMin = float64(1)
+	auditLimitMax = float64(20)
+)
+
+// /whitelist-admin undeny の番号の下限
+var denyIDMin = float64(1)
+
+// Commands は登録対象のコマンド一覧
+// → ApplicationCommandCreate 時にも、ハンドラ側の分岐にもこれを使う。
+var Commands = []CommandDef{
+	{
+		Name:        CommandPing,
+		Description: "Botの疎通確認を行う。",
+	},
+	{
+		Name:        CommandWhitelist,
+		Description: "自分のホワイトリスト状態を確認・編集する。",
+	},
+	{
+		Name:                     CommandWhitelistAdmin,
+		Description:              "他ユーザーのホワイトリストを管理する（管理者向け）。",
+		DefaultMemberPermissions: &adminPermissions,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        SubcommandWhitelistAdd,
+				Description: "ユーザーの VRChat アカウントを登録・変更する。",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "user",
+						Description: "対象ユーザー",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "vrc_name",
+						Description: "VRChat の表示名 / ユーザーID（usr_...） / プロフィールURL",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        SubcommandWhitelistRemove,
+				Description: "ユーザーのホワイトリスト登録を削除する（restore で戻せる）。",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "user",
+						Description: "対象ユーザー",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        SubcommandWhitelistShow,
+				Description: "ユーザーのホワイトリスト状態を表示する。",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "user",
+						Description: "対象ユーザー",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        SubcommandWhitelistLookup,
+				Description: "VRChat 名（部分一致）かユーザーID / URL から登録者を探す。",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "vrc_name_or_id",
+						Description: "VRChat の表示名 / ユーザーID（usr_...） / プロフィールURL",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        SubcommandWhitelistNote,
+				Description: "ユーザーの登録に管理者用のメモを付ける（本人には見えない）。",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "user",
+						Description: "対象ユーザー",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        SubcommandWhitelistExpire,
+				Description: "ホワイトリストの有効期限を設定する。期限を省略すると無期限に戻す。",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "user",
+						Description: "対象ユーザー",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "expires_at",
+						Description: "期限（例: 2026-11-01 23:59 ※日本時間）",
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        SubcommandWhitelistAudit,
+				Description: "ホワイトリスト変更の監査ログを新しい順に表示する。",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "user",
+						Description: "対象ユーザーで絞り込む",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "limit",
+						Description: "表示件数（最大20）",
+						MinValue:    &auditLimitMin,
+						MaxValue:    auditLimitMax,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        SubcommandWhitelistRestore,
+				Description: "削除されたホワイトリスト登録を元に戻す（リストの所属も戻る）。",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "user",
+						Description: "対象ユーザー",
+						Required:    true,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        SubcommandWhitelistImport,
+				Description: "CSV（Discord ID と VRChat名の列）からまとめて登録する。",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionAttachment,
+						Name:        "file",
+						Description: "CSVファイル（1行目はヘッダー）",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "dry_run",
+						Description: "true なら登録せずに結果だけ表示する",
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        SubcommandWhitelistExport,
+				Description: "登録の全件をファイルで受け取る（スプレッドシート用）。",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "format",
+						Description: "ファイル形式（省略時は CSV）",
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{Name: "CSV", Value: "csv"},
+							{Name: "NDJSON", Value: "ndjson"},
+						},
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        SubcommandWhitelistReconcile,
+				Description: "サーバー全員のホワイトリスト用ロールを登録状況に合わせて付け直す。",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        SubcommandWhitelistDeny,
+				Description: "Discord アカウントか VRChat アカウントを登録禁止にする（今の登録も外れる）。",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "user",
+						Description: "禁止する Discord ユーザー",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "vrc",
+						Description: "禁止する VRChat アカウント（表示名 / usr_... / プロフィールURL）",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "reason",
+						Description: "理由（管理者だけが見る）",
+						MaxLength:   255,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        SubcommandWhitelistUndeny,
+				Description: "登録禁止を解く（外れた登録は戻らない。必要なら restore する）。",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Name:        "id",
+						Description: "denylist に出る番号",
+						Required:    true,
+						MinValue:    &denyIDMin,
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        SubcommandWhitelistDenylist,
+				Description: "登録禁止リストを新しい順に表示する。",
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        SubcommandWhitelistWindow,
+				Description: "登録の受付期間を表示・設定する（日時を省略すると今の設定を表示）。",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "list",
+						Description: "リスト名（省略でホワイトリスト全体）",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "opens_at",
+						Description: "受付開始（例: 2026-11-01 20:00 ※日本時間）",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "closes_at",
+						Description: "受付終了（例: 2026-11-07 23:59 ※日本時間）",
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "clear",
+						Description: "true なら受付期間を外してから設定する（日時も省略すれば制限なし）",
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        SubcommandWhitelistOverride,
+				Description: "受付期間の外でも登録・リスト参加できるよう個別に許可する。",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionUser,
+						Name:        "user",
+						Description: "対象ユーザー",
+						Required:    true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionBoolean,
+						Name:        "revoke",
+						Description: "true なら許可を取り消す",
+					},
+				},
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Name:        SubcommandWhitelistWaitlist,
+				Description: "定員の埋まり具合とキャンセル待ちの列を表示する。",
+			},
+		},
+	},
+	// 将来的な拡張:
+	// {
+	// 	Name:        CommandTournament,
+	// 	Description: "大会関連の操作を行う。",
+	// },
+}
@@ -1,54 +1,95 @@
-package discord
-
-import (
-	"backend/internal/service"
-
-	"github.com/bwmarrin/discordgo"
-)
-
-// Router は Discord の Interaction を各処理に振り分ける役割。
-type Router struct {
-	WhitelistService service.WhitelistService
-	// TournamentService service.TournamentService
-	// CypherService     service.CypherService
-	// BeatService       service.BeatService
-}
-
-// NewRouter で必要な service を DI。
-func NewRouter(
-	whitelistService service.WhitelistService,
-	// tournamentService service.TournamentService,
-	// cypherService service.CypherService,
-	// beatService service.BeatService,
-) *Router {
-	return &Router{
-		WhitelistService: whitelistService,
-		// TournamentService: tournamentService,
-		// CypherService:     cypherService,
-		// BeatService:       beatService,
-	}
-}
-
-// HandleInteraction は discordgo のイベントハンドラとして登録される入口。
-// main.go 側で: session.AddHandler(router.HandleInteraction)
-func (r *Router) HandleInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	switch i.Type {
-
-	case discordgo.InteractionApplicationCommand:
-		data := i.ApplicationCommandData()
-		cmd := CommandName(data.Name)
-
-		switch cmd {
-		case CommandPing:
-			r.handlePing(s, i)
-		case CommandWhitelist:
-			r.handleWhitelistPanel(s, i)
-		}
-
-	case discordgo.InteractionMessageComponent:
-		r.handleWhitelistComponent(s, i)
-
-	case discordgo.InteractionModalSubmit:
-		r.handleWhitelistModalSubmit(s, i)
-	}
-}
+package discord
+
+import (
+	"backend/internal/service"
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Router は Discord の Interaction を各処理に振り分ける役割。
+type Router struct {
+	WhitelistService service.WhitelistService
+	RoleGate         service.RoleGate
+	// ホワイトリスト用ロールの突き合わせ。ロール未設定なら nil
+	RoleSyncer *RoleSyncer
+	// 退出・BAN を見張るサーバー（DISCORD_GUILD_ID と、必要ロール・ホワイトリスト用ロールを設定したサーバー）。
+	// 空なら何もしない。
+	MemberGuildIDs []string
+
+	// 削除確認ボタンのワンタイムトークン
+	deleteTokens *confirmTokens
+	// TournamentService service.TournamentService
+	// CypherService     service.CypherService
+	// BeatService       service.BeatService
+}
+
+// NewRouter で必要な service を DI。
+func NewRouter(
+	whitelistService service.WhitelistService,
+	roleGate service.RoleGate,
+	roleSyncer *RoleSyncer,
+	guildID string,
+	// tournamentService service.TournamentService,
+	// cypherService service.CypherService,
+	// beatService service.BeatService,
+) *Router {
+	return &Router{
+		WhitelistService: whitelistService,
+		RoleGate:         roleGate,
+		RoleSyncer:       roleSyncer,
+		MemberGuildIDs:   memberGuilds(guildID, roleGate, roleSyncer),
+		deleteTokens:     newConfirmTokens(),
+		// TournamentService: tournamentService,
+		// CypherService:     cypherService,
+		// BeatService:       beatService,
+	}
+}
+
+// 設定に出てくるサーバーを重複なしで集める
+func memberGuilds(guildID string, roleGate service.RoleGate, roleSyncer *RoleSyncer) []string {
+	var guilds []string
+	if guildID != "" {
+		guilds = append(guilds, guildID)
+	}
+	if roleGate != nil {
+		guilds = append(guilds, roleGate.Guilds()...)
+	}
+	if roleSyncer != nil {
+		guilds = append(guilds, roleSyncer.Guilds()...)
+	}
+	slices.Sort(guilds)
+	return slices.Compact(guilds)
+}
+
+// HandleInteraction は discordgo のイベントハンドラとして登録される入口。
+// main.go 側で: session.AddHandler(router.HandleInteraction)
+func (r *Router) HandleInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	switch i.Type {
+
+	case discordgo.InteractionApplicationCommand:
+		data := i.ApplicationCommandData()
+		cmd := CommandName(data.Name)
+
+		switch cmd {
+		case CommandPing:
+			r.handlePing(s, i)
+		case CommandWhitelist:
+			r.handleWhitelistPanel(s, i)
+		case CommandWhitelistAdmin:
+			r.handleWhitelistAdmin(s, i)
+		}
+
+	case discordgo.InteractionMessageComponent:
+		r.handleWhitelistComponent(s, i)
+
+	case discordgo.InteractionModalSubmit:
+		// 管理者用メモのモーダルは custom ID に対象の Discord ID が付いている
+		if targetID, ok := strings.CutPrefix(i.ModalSubmitData().CustomID, modalWhitelistNote+":"); ok {
+			r.handleWhitelistNoteSubmit(s, i, targetID)
+			return
+		}
+		r.handleWhitelistModalSubmit(s, i)
+	}
+}
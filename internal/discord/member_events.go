@@ -0,0 +1,119 @@
+package discord
+
+import (
+	"backend/internal/service"
+	"context"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// サーバーを抜けた人の紐付けを外す。main.go 側で: session.AddHandler(router.HandleGuildMemberRemove)
+// BAN でも抜けたことになってこちらが飛んでくるので、BAN されているかを見て理由を分ける。
+// BAN は GuildBanAdd も飛んでくるが、二重に扱わないようこちらだけで見る。
+// 見張っている他のサーバーにまだいる人は外さない。
+func (r *Router) HandleGuildMemberRemove(s *discordgo.Session, e *discordgo.GuildMemberRemove) {
+	if e.Member == nil || e.User == nil || !r.watchesGuild(e.GuildID) {
+		return
+	}
+
+	stillMember, err := r.inOtherGuild(s, e.GuildID, e.User.ID)
+	if err != nil {
+		// 確認できないときは外さない。起動時の突き合わせで拾う。
+		log.Printf("GuildMember lookup failed (discord_user_id=%s): %+v", e.User.ID, err)
+		return
+	}
+	if stillMember {
+		return
+	}
+
+	reason := service.AuditActionLeftGuild
+	// BAN 一覧を見るには「メンバーをBAN」権限が要る。見られなければ退出扱い。
+	if _, err := s.GuildBan(e.GuildID, e.User.ID); err == nil {
+		reason = service.AuditActionBanned
+	}
+	r.removeDepartedMember(e.User.ID, reason)
+}
+
+// 起動時の突き合わせ: Bot が止まっていた間に抜けた人の紐付けを外し、外した人数を返す。
+// 見張っているどのサーバーにもいない人だけ外す。本人確認待ちの紐付けは期限で消えるので見ない。
+func (r *Router) CatchUpMembers(ctx context.Context, session Session) (int, error) {
+	if len(r.MemberGuildIDs) == 0 {
+		return 0, nil
+	}
+
+	members := make(map[string]bool)
+	for _, guildID := range r.MemberGuildIDs {
+		n := 0
+		err := session.EachGuildMember(ctx, guildID, func(m *discordgo.Member) error {
+			if m.User != nil {
+				members[m.User.ID] = true
+				n++
+			}
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+		// 取れたメンバーが0人なのは権限や Intent の設定ミス。全員を外してしまわないよう何もしない。
+		if n == 0 {
+			return 0, fmt.Errorf("guild %s returned no members", guildID)
+		}
+	}
+
+	users, err := r.WhitelistService.ListWhitelist(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, u := range users {
+		if members[u.DiscordUserID] {
+			continue
+		}
+		ok, err := r.WhitelistService.RemoveDiscordForReason(ctx, u.DiscordUserID, service.AuditActionLeftGuild)
+		if err != nil {
+			return removed, err
+		}
+		if ok {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// 設定に出てくるサーバーのイベントだけ扱う
+func (r *Router) watchesGuild(guildID string) bool {
+	return slices.Contains(r.MemberGuildIDs, guildID)
+}
+
+// 見張っている guildID 以外のサーバーにまだいるか
+func (r *Router) inOtherGuild(s *discordgo.Session, guildID, userID string) (bool, error) {
+	for _, g := range r.MemberGuildIDs {
+		if g == guildID {
+			continue
+		}
+		if _, err := s.GuildMember(g, userID); err != nil {
+			if isUnknownMember(err) {
+				continue
+			}
+			return false, err
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+func (r *Router) removeDepartedMember(discordID, reason string) {
+	// 監査ログの actor は system（Discord 側のイベントなので操作した人は分からない）
+	removed, err := r.WhitelistService.RemoveDiscordForReason(context.Background(), discordID, reason)
+	if err != nil {
+		log.Printf("RemoveDiscordForReason internal error (discord_user_id=%s, reason=%s): %+v", discordID, reason, err)
+		return
+	}
+	if removed {
+		log.Printf("whitelist: removed %s (%s)", discordID, reason)
+	}
+}
@@ -0,0 +1,20 @@
+package discord
+
+import "context"
+
+// 管理チャンネルへの通知。worker.Notifier を満たす。
+type ChannelNotifier struct {
+	session   Session
+	channelID string
+}
+
+func NewChannelNotifier(s Session, channelID string) *ChannelNotifier {
+	return &ChannelNotifier{session: s, channelID: channelID}
+}
+
+func (n *ChannelNotifier) Notify(ctx context.Context, msg string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return n.session.SendMessage(n.channelID, msg)
+}
@@ -0,0 +1,115 @@
+package discord
+
+import (
+	"context"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// ホワイトリストに載っている人に付けるロール（サーバーごと）。service.WhitelistRoleSyncer を満たす。
+// Bot のロールが付け外しするロールより上にあって、「ロールの管理」権限を持っている必要がある。
+type RoleSyncer struct {
+	session Session
+	// guild ID → role ID
+	roles map[string]string
+}
+
+// DISCORD_WHITELISTED_ROLE_IDS で設定する。
+// 形式: <guild_id>:<role_id>;<guild_id>:<role_id>
+func NewRoleSyncer(s Session) *RoleSyncer {
+	return &RoleSyncer{
+		session: s,
+		roles:   parseWhitelistedRoles(os.Getenv("DISCORD_WHITELISTED_ROLE_IDS")),
+	}
+}
+
+func parseWhitelistedRoles(v string) map[string]string {
+	roles := make(map[string]string)
+	for _, entry := range strings.Split(v, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		guildID, roleID, ok := strings.Cut(entry, ":")
+		guildID, roleID = strings.TrimSpace(guildID), strings.TrimSpace(roleID)
+		if !ok || guildID == "" || roleID == "" {
+			log.Printf("invalid DISCORD_WHITELISTED_ROLE_IDS entry %q, skipped", entry)
+			continue
+		}
+		roles[guildID] = roleID
+	}
+	return roles
+}
+
+// どこかのサーバーでロールが設定されているか
+func (r *RoleSyncer) Enabled() bool {
+	return len(r.roles) > 0
+}
+
+// ロールが設定されているサーバーの ID（昇順）
+func (r *RoleSyncer) Guilds() []string {
+	return slices.Sorted(maps.Keys(r.roles))
+}
+
+// そのサーバーで付け外しするロールID。設定が無ければ空。
+func (r *RoleSyncer) RoleID(guildID string) string {
+	return r.roles[guildID]
+}
+
+// 設定のある全サーバーで、ロールを付ける（whitelisted = true）か外す。
+// サーバーにいない人は飛ばす。
+func (r *RoleSyncer) SetWhitelisted(ctx context.Context, discordID string, whitelisted bool) error {
+	for guildID, roleID := range r.roles {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		var err error
+		if whitelisted {
+			err = r.session.AddMemberRole(guildID, discordID, roleID)
+		} else {
+			err = r.session.RemoveMemberRole(guildID, discordID, roleID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// サーバーの全メンバーのロールをホワイトリストに合わせる。
+// whitelisted はホワイトリストに載っている Discord ID。付けた人数・外した人数を返す。
+func (r *RoleSyncer) Reconcile(ctx context.Context, guildID string, whitelisted map[string]bool) (added, removed int, err error) {
+	roleID := r.roles[guildID]
+	if roleID == "" {
+		return 0, 0, nil
+	}
+
+	err = r.session.EachGuildMember(ctx, guildID, func(m *discordgo.Member) error {
+		if m.User == nil || m.User.Bot {
+			return nil
+		}
+		has := slices.Contains(m.Roles, roleID)
+		want := whitelisted[m.User.ID]
+
+		switch {
+		case want && !has:
+			if err := r.session.AddMemberRole(guildID, m.User.ID, roleID); err != nil {
+				return err
+			}
+			added++
+		case !want && has:
+			if err := r.session.RemoveMemberRole(guildID, m.User.ID, roleID); err != nil {
+				return err
+			}
+			removed++
+		}
+		return nil
+	})
+	return added, removed, err
+}
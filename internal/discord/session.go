@@ -1,105 +1,195 @@
-package discord
-
-import (
-	"context"
-	"fmt"
-	"time"
-
-	"github.com/bwmarrin/discordgo"
-)
-
-type Session interface {
-	Start(ctx context.Context) error
-	Close() error
-	AddHandler(handler any)
-	RegisterCommands(ctx context.Context, appID, guildID string) error
-}
-
-type session struct {
-	dg      *discordgo.Session
-	started bool
-}
-
-// 固定で使うIntent。
-const defaultIntents = discordgo.IntentsGuilds |
-	discordgo.IntentsGuildMessages
-
-// discordgo.Sessionを組み立てる
-func NewSession(token string) (Session, error) {
-	if token == "" {
-		return nil, fmt.Errorf("discord token is empty")
-	}
-
-	// Sessionを作る
-	dg, err := discordgo.New("Bot " + token)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create discord session: %w", err)
-	}
-
-	dg.Identify.Intents = defaultIntents
-
-	return &session{dg: dg}, nil
-}
-
-// Gatewayに実際に接続してイベント受信を開始する
-func (s *session) Start(ctx context.Context) error {
-	if s.started {
-		return nil
-	}
-
-	// Gateway接続
-	if err := s.dg.Open(); err != nil {
-		return fmt.Errorf("failed to open discord session: %w", err)
-	}
-
-	// 軽く待って安定させる（好みで削ってOK）
-	select {
-	case <-ctx.Done():
-		_ = s.dg.Close()
-		return ctx.Err()
-	case <-time.After(500 * time.Millisecond):
-	}
-
-	s.started = true
-	return nil
-}
-
-func (s *session) Close() error {
-	if !s.started {
-		return nil
-	}
-	s.started = false
-	return s.dg.Close()
-}
-
-// 「この関数は、この種類のイベントが来たら呼んでね」とSessionに登録　ラッパー
-func (s *session) AddHandler(handler any) {
-	// discordgo イベント登録
-	s.dg.AddHandler(handler)
-}
-
-// コマンド登録
-func (s *session) RegisterCommands(ctx context.Context, appID, guildID string) error {
-	if appID == "" {
-		return fmt.Errorf("discord app id is empty")
-	}
-
-	for _, c := range Commands {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
-
-		_, err := s.dg.ApplicationCommandCreate(appID, guildID, &discordgo.ApplicationCommand{
-			Name:        string(c.Name),
-			Description: c.Description,
-			Options:     c.Options,
-		})
-		if err != nil {
-			return fmt.Errorf("failed to create command %s: %w", c.Name, err)
-		}
-	}
-
-	return nil
-}
+package discord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type Session interface {
+	Start(ctx context.Context) error
+	Close() error
+	AddHandler(handler any)
+	RegisterCommands(ctx context.Context, appID, guildID string) error
+	SendMessage(channelID, content string) error
+	SendDirectMessage(userID, content string) error
+	GuildMemberRoles(guildID, userID string) ([]string, error)
+	AddMemberRole(guildID, userID, roleID string) error
+	RemoveMemberRole(guildID, userID, roleID string) error
+	EachGuildMember(ctx context.Context, guildID string, fn func(m *discordgo.Member) error) error
+}
+
+type session struct {
+	dg      *discordgo.Session
+	started bool
+}
+
+// 固定で使うIntent。
+// GuildMembers は特権 Intent（Developer Portal で Server Members Intent を ON にする）。OFF のままだと接続が 4014 で拒否される。
+// ホワイトリスト用ロールの突き合わせ・退出と BAN の検知でメンバー一覧を取るのに要る。
+const defaultIntents = discordgo.IntentsGuilds |
+	discordgo.IntentsGuildMessages |
+	discordgo.IntentsGuildMembers
+
+// discordgo.Sessionを組み立てる
+func NewSession(token string) (Session, error) {
+	if token == "" {
+		return nil, fmt.Errorf("discord token is empty")
+	}
+
+	// Sessionを作る
+	dg, err := discordgo.New("Bot " + token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create discord session: %w", err)
+	}
+
+	dg.Identify.Intents = defaultIntents
+
+	return &session{dg: dg}, nil
+}
+
+// Gatewayに実際に接続してイベント受信を開始する
+func (s *session) Start(ctx context.Context) error {
+	if s.started {
+		return nil
+	}
+
+	// Gateway接続
+	if err := s.dg.Open(); err != nil {
+		return fmt.Errorf("failed to open discord session: %w", err)
+	}
+
+	// 軽く待って安定させる（好みで削ってOK）
+	select {
+	case <-ctx.Done():
+		_ = s.dg.Close()
+		return ctx.Err()
+	case <-time.After(500 * time.Millisecond):
+	}
+
+	s.started = true
+	return nil
+}
+
+func (s *session) Close() error {
+	if !s.started {
+		return nil
+	}
+	s.started = false
+	return s.dg.Close()
+}
+
+// 「この関数は、この種類のイベントが来たら呼んでね」とSessionに登録　ラッパー
+func (s *session) AddHandler(handler any) {
+	// discordgo イベント登録
+	s.dg.AddHandler(handler)
+}
+
+// コマンド登録
+func (s *session) RegisterCommands(ctx context.Context, appID, guildID string) error {
+	if appID == "" {
+		return fmt.Errorf("discord app id is empty")
+	}
+
+	for _, c := range Commands {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		_, err := s.dg.ApplicationCommandCreate(appID, guildID, &discordgo.ApplicationCommand{
+			Name:                     string(c.Name),
+			Description:              c.Description,
+			Options:                  c.Options,
+			DefaultMemberPermissions: c.DefaultMemberPermissions,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create command %s: %w", c.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// チャンネルにメッセージを送る。メンションは表示だけで通知は飛ばさない。
+func (s *session) SendMessage(channelID, content string) error {
+	_, err := s.dg.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Content:         content,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	return err
+}
+
+// ユーザーに DM を送る。DM を受け付けていない人にはエラーになる。
+func (s *session) SendDirectMessage(userID, content string) error {
+	ch, err := s.dg.UserChannelCreate(userID)
+	if err != nil {
+		return err
+	}
+	return s.SendMessage(ch.ID, content)
+}
+
+// サーバーのメンバーのロールID。サーバーにいなければ nil, nil。service.MemberRoleLookup を満たす。
+func (s *session) GuildMemberRoles(guildID, userID string) ([]string, error) {
+	m, err := s.dg.GuildMember(guildID, userID)
+	if err != nil {
+		if isUnknownMember(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return m.Roles, nil
+}
+
+// メンバーにロールを付ける。サーバーにいなければ何もしない。
+func (s *session) AddMemberRole(guildID, userID, roleID string) error {
+	err := s.dg.GuildMemberRoleAdd(guildID, userID, roleID)
+	if isUnknownMember(err) {
+		return nil
+	}
+	return err
+}
+
+// メンバーからロールを外す。サーバーにいなければ何もしない。
+func (s *session) RemoveMemberRole(guildID, userID, roleID string) error {
+	err := s.dg.GuildMemberRoleRemove(guildID, userID, roleID)
+	if isUnknownMember(err) {
+		return nil
+	}
+	return err
+}
+
+// サーバーの全メンバーを順に fn に渡す。1回のAPIで取れるのは1000人まで。
+func (s *session) EachGuildMember(ctx context.Context, guildID string, fn func(m *discordgo.Member) error) error {
+	const pageSize = 1000
+
+	after := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		members, err := s.dg.GuildMembers(guildID, after, pageSize)
+		if err != nil {
+			return err
+		}
+		for _, m := range members {
+			if err := fn(m); err != nil {
+				return err
+			}
+		}
+		if len(members) < pageSize {
+			return nil
+		}
+		after = members[len(members)-1].User.ID
+	}
+}
+
+// 相手がサーバーにいない（抜けた・そもそも居ない）ときの REST エラーか
+func isUnknownMember(err error) bool {
+	var rerr *discordgo.RESTError
+	return errors.As(err, &rerr) && rerr.Message != nil && rerr.Message.Code == discordgo.ErrCodeUnknownMember
+}
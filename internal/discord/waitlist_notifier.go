@@ -0,0 +1,31 @@
+package discord
+
+import (
+	"backend/internal/models"
+	"context"
+	"fmt"
+)
+
+// キャンセル待ちから繰り上がった人に DM で知らせる。service.WaitlistNotifier を満たす。
+type WaitlistNotifier struct {
+	session Session
+}
+
+func NewWaitlistNotifier(s Session) *WaitlistNotifier {
+	return &WaitlistNotifier{session: s}
+}
+
+func (n *WaitlistNotifier) NotifyPromoted(ctx context.Context, u *models.WhitelistUser) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	msg := fmt.Sprintf("🎉 ホワイトリストに空きが出たので、キャンセル待ちから登録した（VRChat: 「%s」）。", u.VRCDisplayName)
+	if u.VerifiedAt == nil {
+		msg += fmt.Sprintf("\nVRChat のプロフィール（bio）に確認コード `%s` を書いてから、`/whitelist` の `確認` ボタンを押すと登録が完了する。", u.VerifyCode)
+		if u.VerifyUntil != nil {
+			msg += fmt.Sprintf("\n<t:%d:R> までに確認しないと登録は取り消される。", u.VerifyUntil.Unix())
+		}
+	}
+	return n.session.SendDirectMessage(u.DiscordUserID, msg)
+}
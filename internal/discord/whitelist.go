@@ -1,387 +1,685 @@
-package discord
-
-import (
-	"backend/internal/service"
-	"context"
-	"errors"
-	"fmt"
-	"log"
-	"strings"
-
-	"github.com/bwmarrin/discordgo"
-)
-
-const (
-	btnWhitelistRegister = "wl_register"
-	btnWhitelistDelete   = "wl_delete"
-	btnWhitelistRefresh  = "wl_refresh"
-
-	modalWhitelistRegister = "wl_modal_register"
-	modalInputVRCName      = "wl_modal_input_vrc_name"
-)
-
-// discord IDの取得
-func extractUserID(i *discordgo.InteractionCreate) string {
-	if i.Member != nil && i.Member.User != nil {
-		return i.Member.User.ID
-	}
-	if i.User != nil {
-		return i.User.ID
-	}
-	return ""
-}
-
-// 対象Discordユーザー情報取得（ユーザー名＋アイコンURL）
-func extractUserInfo(i *discordgo.InteractionCreate) (id, username, avatarURL string) {
-	var u *discordgo.User
-	if i.Member != nil && i.Member.User != nil {
-		u = i.Member.User
-	} else if i.User != nil {
-		u = i.User
-	}
-	if u == nil {
-		return "", "", ""
-	}
-	return u.ID, u.Username, u.AvatarURL("128")
-}
-
-// /whitelist 実行時: 状態Embed + ボタン
-func (r *Router) handleWhitelistPanel(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	discordID, username, avatarURL := extractUserInfo(i)
-	if discordID == "" {
-		return
-	}
-
-	ctx := context.Background()
-
-	// 現在の紐付け取得（1:1想定）
-	link, err := r.WhitelistService.GetDiscordVRC(ctx, discordID)
-	if err != nil {
-		link = nil
-	}
-
-	allowed := link != nil
-
-	var (
-		names        []string
-		vrcAvatarURL string
-	)
-	if link != nil {
-		if link.VRCDisplayName != "" {
-			names = []string{link.VRCDisplayName}
-		}
-		vrcAvatarURL = link.VRCAvatarURL
-	}
-
-	embed := buildWhitelistEmbed(discordID, username, avatarURL, allowed, names, vrcAvatarURL)
-	components := whitelistButtons()
-
-	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Embeds:     []*discordgo.MessageEmbed{embed},
-			Components: components,
-			Flags:      discordgo.MessageFlagsEphemeral,
-		},
-	})
-}
-
-// ボタン定義
-func whitelistButtons() []discordgo.MessageComponent {
-	return []discordgo.MessageComponent{
-		discordgo.ActionsRow{
-			Components: []discordgo.MessageComponent{
-				&discordgo.Button{
-					CustomID: btnWhitelistRegister,
-					Label:    "登録 / 更新",
-					Style:    discordgo.PrimaryButton,
-				},
-				&discordgo.Button{
-					CustomID: btnWhitelistDelete,
-					Label:    "削除",
-					Style:    discordgo.DangerButton,
-				},
-				&discordgo.Button{
-					CustomID: btnWhitelistRefresh,
-					Label:    "再表示",
-					Style:    discordgo.SecondaryButton,
-				},
-			},
-		},
-	}
-}
-
-// embedの構築
-// names は現状 0 or 1 件想定だが、将来拡張も考えて配列のまま。
-// vrcAvatarURL: whitelist_users に保存した currentAvatarImageUrl を渡す
-func buildWhitelistEmbed(
-	discordID, username, avatarURL string,
-	allowed bool,
-	names []string,
-	vrcAvatarURL string,
-) *discordgo.MessageEmbed {
-	var (
-		title       string
-		description string
-		color       int
-		statusValue string
-	)
-
-	if allowed {
-		title = "✅ ホワイトリスト登録済み"
-		description = "この Discord アカウントは大会用ホワイトリストに登録されている。"
-		color = 0x00cc99
-		statusValue = "✅ 登録済み"
-	} else {
-		title = "❌ ホワイトリスト未登録"
-		description = "VRChat 名を登録してホワイトリストに参加できる状態にする必要がある。"
-		color = 0xff5555
-		statusValue = "❌ 未登録"
-	}
-
-	vrcField := "なし"
-	if len(names) > 0 {
-		vrcField = "- " + strings.Join(names, "\n- ")
-	}
-
-	discordValue := "なし"
-	if discordID != "" {
-		discordValue = "<@" + discordID + ">"
-	}
-
-	embed := &discordgo.MessageEmbed{
-		Title:       title,
-		Description: description + "\n`登録 / 更新` ボタンから VRChat 名を登録・更新できる。",
-		Color:       color,
-		Author: &discordgo.MessageEmbedAuthor{
-			Name:    username + " さんのホワイトリスト状態",
-			IconURL: avatarURL,
-		},
-		Fields: []*discordgo.MessageEmbedField{
-			{
-				Name:  "ステータス",
-				Value: statusValue,
-			},
-			{
-				Name:   "Discord",
-				Value:  discordValue,
-				Inline: true,
-			},
-			{
-				Name:   "紐づいている VRChat 名",
-				Value:  vrcField,
-				Inline: true,
-			},
-		},
-	}
-
-	// VRChatアバター画像（DBに保存された currentAvatarImageUrl）を大きめ表示
-	if vrcAvatarURL != "" {
-		embed.Image = &discordgo.MessageEmbedImage{
-			URL: vrcAvatarURL,
-		}
-	}
-
-	return embed
-}
-
-// ボタン押下
-func (r *Router) handleWhitelistComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	data := i.MessageComponentData()
-	userID := extractUserID(i)
-	if userID == "" {
-		return
-	}
-
-	switch data.CustomID {
-	case btnWhitelistRegister:
-		r.openWhitelistRegisterModal(s, i)
-	case btnWhitelistDelete:
-		r.handleWhitelistDelete(s, i, userID)
-	case btnWhitelistRefresh:
-		r.handleWhitelistRefresh(s, i, userID)
-	}
-}
-
-// 「登録 / 更新」ボタン → VRChat名入力モーダルを開く
-func (r *Router) openWhitelistRegisterModal(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseModal,
-		Data: &discordgo.InteractionResponseData{
-			CustomID: modalWhitelistRegister,
-			Title:    "VRChat名を登録 / 更新",
-			Components: []discordgo.MessageComponent{
-				discordgo.ActionsRow{
-					Components: []discordgo.MessageComponent{
-						&discordgo.TextInput{
-							CustomID:    modalInputVRCName,
-							Label:       "VRChat 上の表示名（displayName）",
-							Style:       discordgo.TextInputShort,
-							Required:    true,
-							Placeholder: "例: 野菜ラップ",
-						},
-					},
-				},
-			},
-		},
-	})
-}
-
-// モーダル submit: VRChat displayName から Search All Users → whitelist_users 更新
-func (r *Router) handleWhitelistModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	data := i.ModalSubmitData()
-	if data.CustomID != modalWhitelistRegister {
-		return
-	}
-
-	discordID, username, avatarURL := extractUserInfo(i)
-	if discordID == "" {
-		return
-	}
-
-	var vrcName string
-	for _, comp := range data.Components {
-		row, ok := comp.(*discordgo.ActionsRow)
-		if !ok {
-			continue
-		}
-		for _, inner := range row.Components {
-			input, ok := inner.(*discordgo.TextInput)
-			if !ok {
-				continue
-			}
-			if input.CustomID == modalInputVRCName {
-				vrcName = strings.TrimSpace(input.Value)
-			}
-		}
-	}
-
-	ctx := context.Background()
-	created, err := r.WhitelistService.RegisterDiscordVRC(ctx, discordID, vrcName)
-
-	var msg string
-	switch {
-	case errors.Is(err, service.ErrInvalidArgument):
-		msg = "VRChat名が空か不正。もう一度入力してくれ。"
-	case errors.Is(err, service.ErrNoExactMatch):
-		msg = "その VRChat名のユーザーはいません。"
-	case errors.Is(err, service.ErrMultipleExactMatch):
-		msg = "同じ VRChat名のユーザーが複数いるため特定できない。"
-	case errors.Is(err, service.ErrAlreadyExists):
-		msg = "その VRChatアカウントは既に別の Discord ユーザーに登録されている。"
-	case err != nil:
-		log.Printf("RegisterDiscordVRC internal error: %+v", err)
-		msg = "内部エラーで登録に失敗した。時間をおいて試してくれ。"
-	case created:
-		msg = "ホワイトリストに登録した。"
-	default:
-		msg = "ホワイトリストの情報を更新した。"
-	}
-
-	link, _ := r.WhitelistService.GetDiscordVRC(ctx, discordID)
-	allowed := link != nil
-
-	var (
-		names        []string
-		vrcAvatarURL string
-	)
-	if link != nil {
-		if link.VRCDisplayName != "" {
-			names = []string{link.VRCDisplayName}
-		}
-		vrcAvatarURL = link.VRCAvatarURL
-	}
-
-	embed := buildWhitelistEmbed(discordID, username, avatarURL, allowed, names, vrcAvatarURL)
-
-	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Content:    msg,
-			Embeds:     []*discordgo.MessageEmbed{embed},
-			Components: whitelistButtons(),
-			Flags:      discordgo.MessageFlagsEphemeral,
-		},
-	})
-
-	// 登録・更新が成功したときは、同じパネルを公開メッセージとして流す
-	if err == nil {
-		mention := "<@" + discordID + ">"
-		publicMsg := ""
-		if created {
-			publicMsg = fmt.Sprintf("✅ %s が VRChat アカウント「%s」でホワイトリストに登録された。", mention, vrcName)
-		} else {
-			publicMsg = fmt.Sprintf("♻️ %s のホワイトリスト情報が更新された。（VRChat: 「%s」）", mention, vrcName)
-		}
-
-		_, ferr := s.FollowupMessageCreate(i.Interaction, false, &discordgo.WebhookParams{
-			Content: publicMsg,
-			Embeds:  []*discordgo.MessageEmbed{embed}, // /whitelist パネルと同じ内容を公開
-			AllowedMentions: &discordgo.MessageAllowedMentions{
-				Parse: []discordgo.AllowedMentionType{
-					discordgo.AllowedMentionTypeUsers, // ユーザーだけメンション
-				},
-			},
-		})
-		if ferr != nil {
-			log.Printf("failed to send public whitelist panel: %+v", ferr)
-		}
-	}
-}
-
-// 「削除」ボタン: この Discord ユーザーのリンクを物理削除
-func (r *Router) handleWhitelistDelete(s *discordgo.Session, i *discordgo.InteractionCreate, userID string) {
-	_, username, avatarURL := extractUserInfo(i)
-
-	ctx := context.Background()
-	err := r.WhitelistService.RemoveDiscord(ctx, userID)
-
-	msg := "ホワイトリストから削除した。"
-	if err != nil {
-		log.Printf("RemoveDiscord internal error: %+v", err)
-		msg = "内部エラーで削除に失敗した。"
-	}
-
-	embed := buildWhitelistEmbed(userID, username, avatarURL, false, nil, "")
-
-	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseUpdateMessage,
-		Data: &discordgo.InteractionResponseData{
-			Content:    msg,
-			Embeds:     []*discordgo.MessageEmbed{embed},
-			Components: whitelistButtons(),
-			Flags:      discordgo.MessageFlagsEphemeral,
-		},
-	})
-}
-
-// 「再表示」ボタン: 現在の状態を取り直してEmbed更新
-func (r *Router) handleWhitelistRefresh(s *discordgo.Session, i *discordgo.InteractionCreate, userID string) {
-	_, username, avatarURL := extractUserInfo(i)
-
-	ctx := context.Background()
-	link, _ := r.WhitelistService.GetDiscordVRC(ctx, userID)
-	allowed := link != nil
-
-	var (
-		names        []string
-		vrcAvatarURL string
-	)
-	if link != nil {
-		if link.VRCDisplayName != "" {
-			names = []string{link.VRCDisplayName}
-		}
-		vrcAvatarURL = link.VRCAvatarURL
-	}
-
-	embed := buildWhitelistEmbed(userID, username, avatarURL, allowed, names, vrcAvatarURL)
-
-	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseUpdateMessage,
-		Data: &discordgo.InteractionResponseData{
-			Embeds:     []*discordgo.MessageEmbed{embed},
-			Components: whitelistButtons(),
-			Flags:      discordgo.MessageFlagsEphemeral,
-		},
-	})
-}
+package discord
+
+import (
+	"backend/internal/models"
+	"backend/internal/service"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	btnWhitelistRegister = "wl_register"
+	btnWhitelistDelete   = "wl_delete"
+	btnWhitelistRefresh  = "wl_refresh"
+	btnWhitelistVerify   = "wl_verify"
+
+	selectWhitelistLists        = "wl_select_lists"
+	selectWhitelistVRCCandidate = "wl_select_vrc_candidate"
+
+	modalWhitelistRegister = "wl_modal_register"
+	modalInputVRCName      = "wl_modal_input_vrc_name"
+)
+
+// discord IDの取得
+func extractUserID(i *discordgo.InteractionCreate) string {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User.ID
+	}
+	if i.User != nil {
+		return i.User.ID
+	}
+	return ""
+}
+
+// 監査ログ用に「Discord から・誰が・どの interaction で」を載せた context
+// source はボタン・選択メニューなら SourceDiscordButton、モーダルなら SourceDiscordModal。
+func actorContext(i *discordgo.InteractionCreate, source string) context.Context {
+	return service.WithActor(context.Background(), service.Actor{
+		ID:        extractUserID(i),
+		Source:    source,
+		RequestID: i.ID,
+		Admin:     isAdmin(i),
+	})
+}
+
+// 対象Discordユーザー情報取得（ユーザー名＋アイコンURL）
+func extractUserInfo(i *discordgo.InteractionCreate) (id, username, avatarURL string) {
+	var u *discordgo.User
+	if i.Member != nil && i.Member.User != nil {
+		u = i.Member.User
+	} else if i.User != nil {
+		u = i.User
+	}
+	if u == nil {
+		return "", "", ""
+	}
+	return u.ID, u.Username, u.AvatarURL("128")
+}
+
+// /whitelist 実行時: 状態Embed + ボタン
+func (r *Router) handleWhitelistPanel(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	discordID, username, avatarURL := extractUserInfo(i)
+	if discordID == "" {
+		return
+	}
+
+	ctx := context.Background()
+	embed, components := r.whitelistPanel(ctx, discordID, username, avatarURL)
+
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: components,
+			Flags:      discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// 現在の紐付け・所属リストを取り直して、パネルの Embed とコンポーネントを組み立てる
+func (r *Router) whitelistPanel(ctx context.Context, discordID, username, avatarURL string) (*discordgo.MessageEmbed, []discordgo.MessageComponent) {
+	// 現在の紐付け取得（1:1想定）
+	link, err := r.WhitelistService.GetDiscordVRC(ctx, discordID)
+	if err != nil {
+		log.Printf("GetDiscordVRC internal error: %+v", err)
+		link = nil
+	}
+
+	allowed := link != nil
+
+	var (
+		names        []string
+		vrcAvatarURL string
+		joined       []string
+		expiresAt    *time.Time
+		verify       *pendingVerify
+	)
+	if link != nil {
+		if link.VRCDisplayName != "" {
+			names = []string{link.VRCDisplayName}
+		}
+		vrcAvatarURL = link.VRCAvatarURL
+		expiresAt = link.ExpiresAt
+		if link.VerifiedAt == nil {
+			verify = &pendingVerify{code: link.VerifyCode, until: link.VerifyUntil}
+		}
+
+		lists, err := r.WhitelistService.GetListsByDiscord(ctx, discordID)
+		if err != nil {
+			log.Printf("GetListsByDiscord internal error: %+v", err)
+		}
+		for _, l := range lists {
+			joined = append(joined, l.Name)
+		}
+	}
+
+	// 受付期間外なら「登録 / 更新」ボタンを押せなくする（個別に許可された人は押せる）
+	reg := r.registrationStatus(ctx, discordID)
+
+	embed := buildWhitelistEmbed(discordID, username, avatarURL, allowed, names, vrcAvatarURL, joined, expiresAt, verify)
+	if field := reg.embedField(); field != nil {
+		embed.Fields = append(embed.Fields, field)
+	}
+	// 定員で止められていればキャンセル待ちの順番を出す
+	if !allowed {
+		if field := r.waitlistField(ctx, discordID); field != nil {
+			embed.Fields = append(embed.Fields, field)
+		}
+	}
+	components := whitelistButtons(verify != nil, !reg.open())
+
+	// 紐付け済みなら、本人が参加・脱退できるリストの選択メニューを出す
+	if allowed {
+		lists, err := r.WhitelistService.GetLists(ctx)
+		if err != nil {
+			log.Printf("GetLists internal error: %+v", err)
+		}
+		if row := whitelistListSelect(lists, joined, reg.override); row != nil {
+			components = append(components, row)
+		}
+	}
+
+	return embed, components
+}
+
+// self_service なリストの複数選択メニュー。該当リストが無ければ nil。
+// 選択済み = 所属中 として表示し、送信された選択との差分で参加・脱退する。
+// 受付期間外のリストは名前に「受付終了」を付ける（override = 個別に許可されていれば付けない）。
+func whitelistListSelect(lists []models.WhitelistList, joined []string, override bool) discordgo.MessageComponent {
+	now := time.Now()
+	options := make([]discordgo.SelectMenuOption, 0)
+	for _, l := range lists {
+		if !l.SelfService {
+			continue
+		}
+		// Discord の選択肢は最大25件
+		if len(options) == 25 {
+			break
+		}
+		opt := discordgo.SelectMenuOption{
+			Label:   l.Name,
+			Value:   l.Name,
+			Default: slices.Contains(joined, l.Name),
+		}
+		if !override && !l.RegistrationWindow.IsOpen(now) {
+			opt.Label += "（受付終了）"
+		}
+		if l.Description != "" {
+			// 選択肢の説明は100文字まで（リストの説明は255文字まで入る）
+			opt.Description = truncateRunes(l.Description, 100)
+		}
+		options = append(options, opt)
+	}
+	if len(options) == 0 {
+		return nil
+	}
+
+	minValues := 0
+	return discordgo.ActionsRow{
+		Components: []discordgo.MessageComponent{
+			discordgo.SelectMenu{
+				MenuType:    discordgo.StringSelectMenu,
+				CustomID:    selectWhitelistLists,
+				Placeholder: "参加するリストを選ぶ",
+				MinValues:   &minValues,
+				MaxValues:   len(options),
+				Options:     options,
+			},
+		},
+	}
+}
+
+// 本人確認待ちの紐付けの、bio に書いてもらうコードと期限
+type pendingVerify struct {
+	code  string
+	until *time.Time
+}
+
+// ボタン定義。本人確認待ちなら「確認」ボタンも出す。受付期間外（closed）なら「登録 / 更新」は押せない。
+func whitelistButtons(pending, closed bool) []discordgo.MessageComponent {
+	buttons := []discordgo.MessageComponent{
+		&discordgo.Button{
+			CustomID: btnWhitelistRegister,
+			Label:    "登録 / 更新",
+			Style:    discordgo.PrimaryButton,
+			Disabled: closed,
+		},
+	}
+	if pending {
+		buttons = append(buttons, &discordgo.Button{
+			CustomID: btnWhitelistVerify,
+			Label:    "確認",
+			Style:    discordgo.SuccessButton,
+		})
+	}
+	buttons = append(buttons,
+		&discordgo.Button{
+			CustomID: btnWhitelistDelete,
+			Label:    "削除",
+			Style:    discordgo.DangerButton,
+		},
+		&discordgo.Button{
+			CustomID: btnWhitelistRefresh,
+			Label:    "再表示",
+			Style:    discordgo.SecondaryButton,
+		},
+	)
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: buttons},
+	}
+}
+
+// embedの構築
+// names は現状 0 or 1 件想定だが、将来拡張も考えて配列のまま。
+// vrcAvatarURL: whitelist_users に保存した currentAvatarImageUrl を渡す
+// lists: 所属している名前付きリスト名
+// expiresAt: 有効期限（nil なら無期限）
+// verify: 本人確認待ちならコードと期限（確認済み・未登録なら nil）
+func buildWhitelistEmbed(
+	discordID, username, avatarURL string,
+	allowed bool,
+	names []string,
+	vrcAvatarURL string,
+	lists []string,
+	expiresAt *time.Time,
+	verify *pendingVerify,
+) *discordgo.MessageEmbed {
+	var (
+		title       string
+		description string
+		color       int
+		statusValue string
+	)
+
+	switch {
+	case allowed && verify != nil:
+		title = "⏳ 本人確認待ち"
+		description = "VRChat アカウントの本人確認が済むまでホワイトリストには載らない。\n" +
+			"VRChat のプロフィール（bio）に下の確認コードを書いてから `確認` ボタンを押してくれ（確認後は消してOK）。"
+		color = 0xffb020
+		statusValue = "⏳ 本人確認待ち"
+	case allowed:
+		title = "✅ ホワイトリスト登録済み"
+		description = "この Discord アカウントは大会用ホワイトリストに登録されている。"
+		color = 0x00cc99
+		statusValue = "✅ 登録済み"
+	default:
+		title = "❌ ホワイトリスト未登録"
+		description = "VRChat 名を登録してホワイトリストに参加できる状態にする必要がある。"
+		color = 0xff5555
+		statusValue = "❌ 未登録"
+	}
+
+	vrcField := "なし"
+	if len(names) > 0 {
+		vrcField = "- " + strings.Join(names, "\n- ")
+	}
+
+	listField := "なし"
+	if len(lists) > 0 {
+		listField = "`" + strings.Join(lists, "` `") + "`"
+	}
+
+	discordValue := "なし"
+	if discordID != "" {
+		discordValue = "<@" + discordID + ">"
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       title,
+		Description: description + "\n`登録 / 更新` ボタンから VRChat 名を登録・更新できる。",
+		Color:       color,
+		Author: &discordgo.MessageEmbedAuthor{
+			Name:    username + " さんのホワイトリスト状態",
+			IconURL: avatarURL,
+		},
+		Fields: []*discordgo.MessageEmbedField{
+			{
+				Name:  "ステータス",
+				Value: statusValue,
+			},
+			{
+				Name:   "Discord",
+				Value:  discordValue,
+				Inline: true,
+			},
+			{
+				Name:   "紐づいている VRChat 名",
+				Value:  vrcField,
+				Inline: true,
+			},
+			{
+				Name:  "所属リスト",
+				Value: listField,
+			},
+		},
+	}
+
+	// 本人確認待ちならコードと期限を出す
+	if verify != nil {
+		value := "`" + verify.code + "`"
+		if verify.until != nil {
+			value += fmt.Sprintf("\n<t:%d:R> までに確認しないと登録は取り消される。", verify.until.Unix())
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  "確認コード",
+			Value: value,
+		})
+	}
+
+	// 登録済みなら有効期限も出す（Discord のタイムスタンプ表記で各自のタイムゾーンに合わせて表示される）
+	if allowed {
+		expiresValue := "無期限"
+		if expiresAt != nil {
+			expiresValue = fmt.Sprintf("<t:%d:F>（<t:%d:R>）", expiresAt.Unix(), expiresAt.Unix())
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  "有効期限",
+			Value: expiresValue,
+		})
+	}
+
+	// VRChatアバター画像（DBに保存された currentAvatarImageUrl）を大きめ表示
+	if vrcAvatarURL != "" {
+		embed.Image = &discordgo.MessageEmbedImage{
+			URL: vrcAvatarURL,
+		}
+	}
+
+	return embed
+}
+
+// ボタン押下
+func (r *Router) handleWhitelistComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.MessageComponentData()
+	userID := extractUserID(i)
+	if userID == "" {
+		return
+	}
+
+	// 削除の確認ボタンは custom ID にトークンが付いている
+	if token, ok := confirmTokenFromCustomID(data.CustomID, btnWhitelistDeleteConfirm); ok {
+		r.handleWhitelistDeleteConfirm(s, i, userID, token)
+		return
+	}
+	if token, ok := confirmTokenFromCustomID(data.CustomID, btnWhitelistDeleteCancel); ok {
+		r.handleWhitelistDeleteCancel(s, i, userID, token)
+		return
+	}
+
+	switch data.CustomID {
+	case btnWhitelistRegister:
+		r.openWhitelistRegisterModal(s, i)
+	case btnWhitelistDelete:
+		r.handleWhitelistDeleteRequest(s, i, userID)
+	case btnWhitelistRefresh:
+		r.handleWhitelistRefresh(s, i, userID)
+	case btnWhitelistVerify:
+		r.handleWhitelistVerify(s, i, userID)
+	case selectWhitelistLists:
+		r.handleWhitelistListSelect(s, i, userID, data.Values)
+	case selectWhitelistVRCCandidate:
+		r.handleWhitelistCandidateSelect(s, i, userID, data.Values)
+	}
+}
+
+// 「登録 / 更新」ボタン → VRChat名入力モーダルを開く
+func (r *Router) openWhitelistRegisterModal(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if !r.checkRegisterRoles(s, i) || !r.checkRegistrationWindow(s, i) || !r.checkRegisterRateLimit(s, i) {
+		return
+	}
+
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: modalWhitelistRegister,
+			Title:    "VRChat名を登録 / 更新",
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						&discordgo.TextInput{
+							CustomID:    modalInputVRCName,
+							Label:       "VRChat の表示名 / ユーザーID / プロフィールURL",
+							Style:       discordgo.TextInputShort,
+							Required:    true,
+							Placeholder: "例: 野菜ラップ / usr_xxxxxxxx-... / https://vrchat.com/home/user/usr_...",
+						},
+					},
+				},
+			},
+		},
+	})
+}
+
+// モーダル submit: VRChat displayName から Search All Users → whitelist_users 更新
+func (r *Router) handleWhitelistModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ModalSubmitData()
+	if data.CustomID != modalWhitelistRegister {
+		return
+	}
+
+	discordID, username, avatarURL := extractUserInfo(i)
+	if discordID == "" {
+		return
+	}
+	if !r.checkRegisterRoles(s, i) {
+		return
+	}
+
+	var vrcName string
+	for _, comp := range data.Components {
+		row, ok := comp.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for _, inner := range row.Components {
+			input, ok := inner.(*discordgo.TextInput)
+			if !ok {
+				continue
+			}
+			if input.CustomID == modalInputVRCName {
+				vrcName = strings.TrimSpace(input.Value)
+			}
+		}
+	}
+
+	ctx := actorContext(i, service.SourceDiscordModal)
+	created, err := r.WhitelistService.RegisterDiscordVRC(ctx, discordID, vrcName)
+
+	// 同名が複数いたら候補から選んでもらう（続きは handleWhitelistCandidateSelect）
+	var multi *service.MultipleMatchError
+	if errors.As(err, &multi) {
+		r.respondVRCCandidates(s, i, multi.Candidates)
+		return
+	}
+
+	r.respondRegisterResult(s, i, ctx, discordgo.InteractionResponseChannelMessageWithSource,
+		discordID, username, avatarURL, created, err)
+}
+
+// 登録結果をパネル付きで本人に返し、成功していれば公開メッセージも流す。
+// respType はモーダルからなら新規メッセージ、候補の選択からなら元メッセージの更新。
+func (r *Router) respondRegisterResult(
+	s *discordgo.Session,
+	i *discordgo.InteractionCreate,
+	ctx context.Context,
+	respType discordgo.InteractionResponseType,
+	discordID, username, avatarURL string,
+	created bool,
+	err error,
+) {
+	// 新規や別アカウントへの付け替えは本人確認待ちになる
+	var link *models.WhitelistUser
+	if err == nil {
+		if u, gerr := r.WhitelistService.GetDiscordVRC(ctx, discordID); gerr == nil {
+			link = u
+		}
+	}
+	pending := link != nil && link.VerifiedAt == nil
+
+	var (
+		msg        string
+		waitlisted *service.WaitlistedError
+		limited    *service.RateLimitError
+	)
+	switch {
+	case errors.Is(err, service.ErrInvalidArgument):
+		msg = "VRChat名が空か不正。もう一度入力してくれ。"
+	case errors.Is(err, service.ErrNoExactMatch):
+		msg = "その VRChat名のユーザーはいません。"
+	case errors.Is(err, service.ErrMultipleExactMatch):
+		msg = "同じ VRChat名のユーザーが複数いるため特定できない。ユーザーID（usr_...）かプロフィールURLで登録してくれ。"
+	case errors.Is(err, service.ErrVRCUserNotFound):
+		msg = "そのユーザーID / URL の VRChat ユーザーはいません。"
+	case errors.Is(err, service.ErrAlreadyExists):
+		msg = "その VRChatアカウントは既に別の Discord ユーザーに登録されている。"
+	case errors.Is(err, service.ErrDenied):
+		msg = "この Discord アカウントかその VRChat アカウントは、ホワイトリストへの登録が禁止されている。心当たりが無ければ運営に問い合わせてくれ。"
+	case errors.Is(err, service.ErrRegistrationClosed):
+		msg = "今はホワイトリストの受付期間外なので、登録・変更できない。"
+	case errors.As(err, &limited):
+		msg = rateLimitMessage(limited)
+	case errors.As(err, &waitlisted):
+		msg = fmt.Sprintf("ホワイトリストが定員に達しているので、キャンセル待ちに登録した（%d 番目）。\n"+
+			"空きが出たら順番に自動で登録して DM で知らせる。", waitlisted.Position)
+	case err != nil:
+		log.Printf("RegisterDiscordVRC internal error: %+v", err)
+		msg = "内部エラーで登録に失敗した。時間をおいて試してくれ。"
+	case pending:
+		msg = "仮登録した。確認コードを VRChat のプロフィール（bio）に書いて `確認` ボタンを押すと登録が完了する。"
+	case created:
+		msg = "ホワイトリストに登録した。"
+	default:
+		msg = "ホワイトリストの情報を更新した。"
+	}
+
+	embed, components := r.whitelistPanel(ctx, discordID, username, avatarURL)
+
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: respType,
+		Data: &discordgo.InteractionResponseData{
+			Content:    msg,
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: components,
+			Flags:      discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	// 登録・更新が成功したときは、同じパネルを公開メッセージとして流す（本人確認待ちなら確認後に流す）
+	if link != nil && !pending {
+		r.announceWhitelist(s, i, discordID, link.VRCDisplayName, embed, created)
+	}
+}
+
+// ホワイトリストに載ったこと・更新されたことを公開メッセージで流す
+func (r *Router) announceWhitelist(
+	s *discordgo.Session,
+	i *discordgo.InteractionCreate,
+	discordID, vrcName string,
+	embed *discordgo.MessageEmbed,
+	created bool,
+) {
+	mention := "<@" + discordID + ">"
+	publicMsg := ""
+	if created {
+		publicMsg = fmt.Sprintf("✅ %s が VRChat アカウント「%s」でホワイトリストに登録された。", mention, vrcName)
+	} else {
+		publicMsg = fmt.Sprintf("♻️ %s のホワイトリスト情報が更新された。（VRChat: 「%s」）", mention, vrcName)
+	}
+
+	_, ferr := s.FollowupMessageCreate(i.Interaction, false, &discordgo.WebhookParams{
+		Content: publicMsg,
+		Embeds:  []*discordgo.MessageEmbed{embed}, // /whitelist パネルと同じ内容を公開
+		AllowedMentions: &discordgo.MessageAllowedMentions{
+			Parse: []discordgo.AllowedMentionType{
+				discordgo.AllowedMentionTypeUsers, // ユーザーだけメンション
+			},
+		},
+	})
+	if ferr != nil {
+		log.Printf("failed to send public whitelist panel: %+v", ferr)
+	}
+}
+
+// 削除の確認で「本当に削除」が押された: この Discord ユーザーのリンクを論理削除（管理者は /whitelist-admin restore で戻せる）
+func (r *Router) handleWhitelistDelete(s *discordgo.Session, i *discordgo.InteractionCreate, userID string) {
+	_, username, avatarURL := extractUserInfo(i)
+
+	ctx := actorContext(i, service.SourceDiscordButton)
+	err := r.WhitelistService.RemoveDiscord(ctx, userID)
+
+	msg := "ホワイトリストから削除した。"
+	if err != nil {
+		log.Printf("RemoveDiscord internal error: %+v", err)
+		msg = "内部エラーで削除に失敗した。"
+	}
+
+	embed, components := r.whitelistPanel(ctx, userID, username, avatarURL)
+
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    msg,
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: components,
+			Flags:      discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// 「再表示」ボタン: 現在の状態を取り直してEmbed更新
+func (r *Router) handleWhitelistRefresh(s *discordgo.Session, i *discordgo.InteractionCreate, userID string) {
+	_, username, avatarURL := extractUserInfo(i)
+
+	ctx := context.Background()
+	embed, components := r.whitelistPanel(ctx, userID, username, avatarURL)
+
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: components,
+			Flags:      discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// リスト選択メニュー: 選ばれたリストに参加、外されたリストから脱退
+// self_service でないリストはここからは触らない。
+func (r *Router) handleWhitelistListSelect(s *discordgo.Session, i *discordgo.InteractionCreate, userID string, selected []string) {
+	_, username, avatarURL := extractUserInfo(i)
+
+	ctx := actorContext(i, service.SourceDiscordButton)
+
+	msg := "所属リストを更新した。"
+	closed, err := r.syncSelfServiceLists(ctx, userID, selected)
+	switch {
+	case errors.Is(err, service.ErrNotRegistered):
+		msg = "先に VRChat 名を登録してくれ。"
+	case err != nil:
+		log.Printf("sync lists internal error: %+v", err)
+		msg = "内部エラーで所属リストの更新に失敗した。"
+	case len(closed) > 0:
+		msg = "`" + strings.Join(closed, "` `") + "` は受付期間外なので参加できなかった。ほかの変更は反映した。"
+	}
+
+	embed, components := r.whitelistPanel(ctx, userID, username, avatarURL)
+
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    msg,
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: components,
+			Flags:      discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// 選択内容と現在の所属の差分を取り、self_service なリストだけ参加・脱退する。
+// 受付期間外で参加できなかったリストの名前を返す（脱退はいつでもできる）。
+func (r *Router) syncSelfServiceLists(ctx context.Context, userID string, selected []string) ([]string, error) {
+	lists, err := r.WhitelistService.GetLists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	current, err := r.WhitelistService.GetListsByDiscord(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	joined := make(map[string]bool, len(current))
+	for _, l := range current {
+		joined[l.Name] = true
+	}
+
+	var closed []string
+	for _, l := range lists {
+		if !l.SelfService {
+			continue
+		}
+		want := slices.Contains(selected, l.Name)
+		switch {
+		case want && !joined[l.Name]:
+			_, err := r.WhitelistService.JoinList(ctx, l.Name, userID)
+			if errors.Is(err, service.ErrRegistrationClosed) {
+				closed = append(closed, l.Name)
+				continue
+			}
+			if err != nil {
+				return closed, err
+			}
+		case !want && joined[l.Name]:
+			if _, err := r.WhitelistService.RemoveFromList(ctx, l.Name, userID); err != nil {
+				return closed, err
+			}
+		}
+	}
+	return closed, nil
+}
@@ -0,0 +1,262 @@
+package discord
+
+import (
+	"backend/internal/models"
+	"backend/internal/service"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// 管理者が入力する日時は日本時間として解釈する
+var jst = time.FixedZone("JST", 9*60*60)
+
+// 管理者か（サーバー管理 or 管理者権限）
+// DefaultMemberPermissions はサーバー側の設定で上書きできるので、実行時にもここで確認する。
+func isAdmin(i *discordgo.InteractionCreate) bool {
+	if i.Member == nil {
+		return false
+	}
+	p := i.Member.Permissions
+	return p&discordgo.PermissionAdministrator != 0 || p&discordgo.PermissionManageGuild != 0
+}
+
+// /whitelist-admin 実行時: サブコマンドごとに振り分け
+func (r *Router) handleWhitelistAdmin(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if !isAdmin(i) {
+		respondEphemeral(s, i, "このコマンドは管理者のみ使える。")
+		return
+	}
+
+	data := i.ApplicationCommandData()
+	if len(data.Options) == 0 {
+		return
+	}
+	sub := data.Options[0]
+
+	switch sub.Name {
+	case SubcommandWhitelistAdd:
+		r.handleWhitelistAdminAdd(s, i, sub)
+	case SubcommandWhitelistRemove:
+		r.handleWhitelistAdminRemove(s, i, sub)
+	case SubcommandWhitelistShow:
+		r.handleWhitelistAdminShow(s, i, sub)
+	case SubcommandWhitelistLookup:
+		r.handleWhitelistAdminLookup(s, i, sub)
+	case SubcommandWhitelistNote:
+		r.handleWhitelistAdminNote(s, i, sub)
+	case SubcommandWhitelistExpire:
+		r.handleWhitelistAdminExpire(s, i, sub)
+	case SubcommandWhitelistAudit:
+		r.handleWhitelistAdminAudit(s, i, sub)
+	case SubcommandWhitelistRestore:
+		r.handleWhitelistAdminRestore(s, i, sub)
+	case SubcommandWhitelistImport:
+		r.handleWhitelistAdminImport(s, i, sub)
+	case SubcommandWhitelistExport:
+		r.handleWhitelistAdminExport(s, i, sub)
+	case SubcommandWhitelistReconcile:
+		r.handleWhitelistAdminReconcile(s, i)
+	case SubcommandWhitelistDeny:
+		r.handleWhitelistAdminDeny(s, i, sub)
+	case SubcommandWhitelistUndeny:
+		r.handleWhitelistAdminUndeny(s, i, sub)
+	case SubcommandWhitelistDenylist:
+		r.handleWhitelistAdminDenylist(s, i)
+	case SubcommandWhitelistWindow:
+		r.handleWhitelistAdminWindow(s, i, sub)
+	case SubcommandWhitelistOverride:
+		r.handleWhitelistAdminWindowOverride(s, i, sub)
+	case SubcommandWhitelistWaitlist:
+		r.handleWhitelistAdminWaitlist(s, i)
+	}
+}
+
+// /whitelist-admin expire user:@user [expires_at:2026-11-01 23:59]
+func (r *Router) handleWhitelistAdminExpire(
+	s *discordgo.Session,
+	i *discordgo.InteractionCreate,
+	sub *discordgo.ApplicationCommandInteractionDataOption,
+) {
+	target := optionUser(i, sub, "user")
+	if target == nil {
+		respondEphemeral(s, i, "対象ユーザーを指定してくれ。")
+		return
+	}
+
+	var expiresAt *time.Time
+	if opt := findOption(sub, "expires_at"); opt != nil {
+		t, err := parseAdminTime(opt.StringValue())
+		if err != nil {
+			respondEphemeral(s, i, "期限の形式が不正。`2026-11-01 23:59`（日本時間）の形で入力してくれ。")
+			return
+		}
+		expiresAt = &t
+	}
+
+	ctx := actorContext(i, service.SourceDiscordCommand)
+	err := r.WhitelistService.SetExpiry(ctx, target.ID, expiresAt)
+
+	var msg string
+	switch {
+	case errors.Is(err, service.ErrInvalidArgument):
+		msg = "期限には未来の日時を指定してくれ。"
+	case errors.Is(err, service.ErrNotRegistered):
+		msg = fmt.Sprintf("<@%s> はホワイトリストに登録されていない。", target.ID)
+	case err != nil:
+		log.Printf("SetExpiry internal error: %+v", err)
+		msg = "内部エラーで期限の設定に失敗した。"
+	case expiresAt == nil:
+		msg = fmt.Sprintf("<@%s> の有効期限を解除した（無期限）。", target.ID)
+	default:
+		msg = fmt.Sprintf("<@%s> の有効期限を <t:%d:F> に設定した。", target.ID, expiresAt.Unix())
+	}
+
+	r.respondAdminPanel(s, i, ctx, msg, target)
+}
+
+// /whitelist-admin audit [user:@user] [limit:10]
+func (r *Router) handleWhitelistAdminAudit(
+	s *discordgo.Session,
+	i *discordgo.InteractionCreate,
+	sub *discordgo.ApplicationCommandInteractionDataOption,
+) {
+	f := models.WhitelistAuditFilter{Limit: 10}
+	if target := optionUser(i, sub, "user"); target != nil {
+		f.DiscordUserID = target.ID
+	}
+	if opt := findOption(sub, "limit"); opt != nil {
+		f.Limit = int(opt.IntValue())
+	}
+
+	page, err := r.WhitelistService.ListAudit(context.Background(), f)
+	if err != nil {
+		log.Printf("ListAudit internal error: %+v", err)
+		respondEphemeral(s, i, "内部エラーで監査ログを取得できなかった。")
+		return
+	}
+	entries := page.Items
+	if len(entries) == 0 {
+		respondEphemeral(s, i, "監査ログはまだ無い。")
+		return
+	}
+
+	lines := make([]string, 0, len(entries))
+	for _, a := range entries {
+		lines = append(lines, formatAuditLine(a))
+	}
+
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{
+				{
+					Title:       "📜 ホワイトリスト監査ログ",
+					Description: strings.Join(lines, "\n"),
+					Color:       0x5865f2,
+				},
+			},
+			Flags:           discordgo.MessageFlagsEphemeral,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	})
+}
+
+// /whitelist-admin restore user:@user
+func (r *Router) handleWhitelistAdminRestore(
+	s *discordgo.Session,
+	i *discordgo.InteractionCreate,
+	sub *discordgo.ApplicationCommandInteractionDataOption,
+) {
+	target := optionUser(i, sub, "user")
+	if target == nil {
+		respondEphemeral(s, i, "対象ユーザーを指定してくれ。")
+		return
+	}
+
+	ctx := actorContext(i, service.SourceDiscordCommand)
+	err := r.WhitelistService.RestoreDiscord(ctx, target.ID)
+
+	var msg string
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		msg = fmt.Sprintf("<@%s> の削除済み登録は見つからなかった（未削除か、保持期間を過ぎて完全に消えている）。", target.ID)
+	case errors.Is(err, service.ErrAlreadyExists):
+		msg = "そのVRChatアカウントは既に別のユーザーが登録しているので戻せない。"
+	case errors.Is(err, service.ErrDenied):
+		msg = "登録禁止リストに載っているので戻せない。戻すなら先に `undeny` で解除してくれ。"
+	case err != nil:
+		log.Printf("RestoreDiscord internal error: %+v", err)
+		msg = "内部エラーで復元に失敗した。"
+	default:
+		msg = fmt.Sprintf("<@%s> のホワイトリスト登録を復元した。", target.ID)
+	}
+
+	r.respondAdminPanel(s, i, ctx, msg, target)
+}
+
+// 監査ログ1件を1行にする
+// 例: <t:...:f> `remove` <@対象> ← <@実行者>（discord_button）
+func formatAuditLine(a models.WhitelistAudit) string {
+	actor := a.Actor
+	// Discord から来た操作なら実行者はユーザーIDなのでメンション表記にする
+	if strings.HasPrefix(a.Source, "discord_") && actor != "" {
+		actor = "<@" + actor + ">"
+	}
+	return fmt.Sprintf("<t:%d:f> `%s` <@%s> ← %s（%s）",
+		a.CreatedAt.Unix(), a.Action, a.DiscordUserID, actor, a.Source)
+}
+
+// 本人にだけ見えるテキストで返す
+func respondEphemeral(s *discordgo.Session, i *discordgo.InteractionCreate, msg string) {
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content:         msg,
+			Flags:           discordgo.MessageFlagsEphemeral,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	})
+}
+
+// サブコマンドのオプションを名前で探す
+func findOption(sub *discordgo.ApplicationCommandInteractionDataOption, name string) *discordgo.ApplicationCommandInteractionDataOption {
+	for _, o := range sub.Options {
+		if o.Name == name {
+			return o
+		}
+	}
+	return nil
+}
+
+// ユーザー型オプションを解決済みユーザー情報として取り出す
+func optionUser(i *discordgo.InteractionCreate, sub *discordgo.ApplicationCommandInteractionDataOption, name string) *discordgo.User {
+	opt := findOption(sub, name)
+	if opt == nil {
+		return nil
+	}
+	id, _ := opt.Value.(string)
+	if id == "" {
+		return nil
+	}
+	if res := i.ApplicationCommandData().Resolved; res != nil {
+		if u, ok := res.Users[id]; ok {
+			return u
+		}
+	}
+	return &discordgo.User{ID: id}
+}
+
+// 管理者が入力した日時を解釈する。RFC3339 か「2006-01-02 15:04」（日本時間）。
+func parseAdminTime(v string) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02 15:04", v, jst)
+}
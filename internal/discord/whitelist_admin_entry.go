@@ -0,0 +1,205 @@
+package discord
+
+import (
+	"backend/internal/service"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// /whitelist-admin lookup で表示する最大件数
+const adminLookupLimit = 10
+
+// /whitelist-admin add user:@user vrc_name:野菜ラップ
+// 本人の /whitelist と同じ確認を通る。監査ログには実行した管理者が残る。
+func (r *Router) handleWhitelistAdminAdd(
+	s *discordgo.Session,
+	i *discordgo.InteractionCreate,
+	sub *discordgo.ApplicationCommandInteractionDataOption,
+) {
+	target := optionUser(i, sub, "user")
+	if target == nil {
+		respondEphemeral(s, i, "対象ユーザーを指定してくれ。")
+		return
+	}
+	var vrcName string
+	if opt := findOption(sub, "vrc_name"); opt != nil {
+		vrcName = strings.TrimSpace(opt.StringValue())
+	}
+
+	ctx := actorContext(i, service.SourceDiscordCommand)
+	created, err := r.WhitelistService.AdminRegisterDiscordVRC(ctx, target.ID, vrcName)
+
+	var (
+		msg   string
+		multi *service.MultipleMatchError
+	)
+	switch {
+	case errors.Is(err, service.ErrInvalidArgument):
+		msg = "VRChat名が空か不正。"
+	case errors.Is(err, service.ErrNoExactMatch):
+		msg = "その VRChat名のユーザーはいない。"
+	case errors.As(err, &multi):
+		msg = "同じ VRChat名のユーザーが複数いる。ユーザーID（usr_...）を指定してやり直してくれ。\n" +
+			formatVRCCandidateLines(multi.Candidates)
+	case errors.Is(err, service.ErrMultipleExactMatch):
+		msg = "同じ VRChat名のユーザーが複数いる。ユーザーID（usr_...）かプロフィールURLを指定してくれ。"
+	case errors.Is(err, service.ErrVRCUserNotFound):
+		msg = "そのユーザーID / URL の VRChat ユーザーはいない。"
+	case errors.Is(err, service.ErrAlreadyExists):
+		msg = "その VRChatアカウントは既に別の Discord ユーザーに登録されている。`lookup` で誰か確認できる。"
+	case errors.Is(err, service.ErrDenied):
+		msg = "その Discord アカウントか VRChat アカウントは登録禁止リストに載っている。登録するなら先に `undeny` で解除してくれ。"
+	case err != nil:
+		log.Printf("RegisterDiscordVRC (admin) internal error: %+v", err)
+		msg = "内部エラーで登録に失敗した。"
+	case created:
+		msg = fmt.Sprintf("<@%s> をホワイトリストに登録した。", target.ID)
+	default:
+		msg = fmt.Sprintf("<@%s> のホワイトリスト情報を更新した。", target.ID)
+	}
+
+	r.respondAdminPanel(s, i, ctx, msg, target)
+}
+
+// /whitelist-admin remove user:@user
+func (r *Router) handleWhitelistAdminRemove(
+	s *discordgo.Session,
+	i *discordgo.InteractionCreate,
+	sub *discordgo.ApplicationCommandInteractionDataOption,
+) {
+	target := optionUser(i, sub, "user")
+	if target == nil {
+		respondEphemeral(s, i, "対象ユーザーを指定してくれ。")
+		return
+	}
+
+	ctx := actorContext(i, service.SourceDiscordCommand)
+
+	link, err := r.WhitelistService.GetDiscordVRC(ctx, target.ID)
+	if err == nil && link == nil {
+		respondEphemeral(s, i, fmt.Sprintf("<@%s> はホワイトリストに登録されていない。", target.ID))
+		return
+	}
+	if err == nil {
+		err = r.WhitelistService.RemoveDiscord(ctx, target.ID)
+	}
+
+	var msg string
+	if err != nil {
+		log.Printf("RemoveDiscord (admin) internal error: %+v", err)
+		msg = "内部エラーで削除に失敗した。"
+	} else {
+		msg = fmt.Sprintf("<@%s> のホワイトリスト登録（VRChat: 「%s」）を削除した。`restore` で戻せる。",
+			target.ID, link.VRCDisplayName)
+	}
+
+	r.respondAdminPanel(s, i, ctx, msg, target)
+}
+
+// /whitelist-admin show user:@user
+func (r *Router) handleWhitelistAdminShow(
+	s *discordgo.Session,
+	i *discordgo.InteractionCreate,
+	sub *discordgo.ApplicationCommandInteractionDataOption,
+) {
+	target := optionUser(i, sub, "user")
+	if target == nil {
+		respondEphemeral(s, i, "対象ユーザーを指定してくれ。")
+		return
+	}
+
+	r.respondAdminPanel(s, i, context.Background(), "", target)
+}
+
+// /whitelist-admin lookup vrc_name_or_id:野菜
+func (r *Router) handleWhitelistAdminLookup(
+	s *discordgo.Session,
+	i *discordgo.InteractionCreate,
+	sub *discordgo.ApplicationCommandInteractionDataOption,
+) {
+	var query string
+	if opt := findOption(sub, "vrc_name_or_id"); opt != nil {
+		query = strings.TrimSpace(opt.StringValue())
+	}
+
+	users, err := r.WhitelistService.LookupVRC(context.Background(), query, adminLookupLimit)
+	switch {
+	case errors.Is(err, service.ErrInvalidArgument):
+		respondEphemeral(s, i, "VRChat名かユーザーIDを入力してくれ。")
+		return
+	case err != nil:
+		log.Printf("LookupVRC internal error: %+v", err)
+		respondEphemeral(s, i, "内部エラーで検索に失敗した。")
+		return
+	case len(users) == 0:
+		respondEphemeral(s, i, "該当する登録は見つからなかった。")
+		return
+	}
+
+	lines := make([]string, 0, len(users))
+	for _, u := range users {
+		line := fmt.Sprintf("<@%s> — %s（`%s`）", u.DiscordUserID, u.VRCDisplayName, u.VRCUserID)
+		if u.VerifiedAt == nil {
+			line += " ⏳ 本人確認待ち"
+		}
+		lines = append(lines, line)
+	}
+
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{
+				{
+					Title:       fmt.Sprintf("🔎 「%s」の検索結果", query),
+					Description: strings.Join(lines, "\n"),
+					Color:       0x5865f2,
+				},
+			},
+			Flags:           discordgo.MessageFlagsEphemeral,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	})
+}
+
+// 対象者の最新のパネル（Embed のみ、管理者メモ付き）を管理者にだけ返す
+func (r *Router) respondAdminPanel(
+	s *discordgo.Session,
+	i *discordgo.InteractionCreate,
+	ctx context.Context,
+	msg string,
+	target *discordgo.User,
+) {
+	embed, _ := r.whitelistPanel(ctx, target.ID, target.Username, target.AvatarURL("128"))
+	if link, err := r.WhitelistService.GetDiscordVRC(ctx, target.ID); err == nil && link != nil {
+		appendNoteField(embed, link.Note)
+	}
+
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: msg,
+			Embeds:  []*discordgo.MessageEmbed{embed},
+			Flags:   discordgo.MessageFlagsEphemeral,
+			// 管理者だけに見えるメッセージだが、対象者への通知は飛ばさない
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	})
+}
+
+// 同名の候補を「表示名（usr_...）」の行にする
+func formatVRCCandidateLines(candidates []service.VRChatUser) string {
+	shown := candidates
+	if len(shown) > maxVRCCandidates {
+		shown = shown[:maxVRCCandidates]
+	}
+	lines := make([]string, 0, len(shown))
+	for _, c := range shown {
+		lines = append(lines, fmt.Sprintf("- %s（`%s`）", c.DisplayName, c.ID))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,87 @@
+package discord
+
+import (
+	"backend/internal/service"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// 候補として見せる最大人数（1メッセージに付けられる embed は10個まで）
+const maxVRCCandidates = 10
+
+// 同じ表示名の VRChat ユーザーが複数いたとき、サムネイルと ID を並べて本人に選んでもらう
+func (r *Router) respondVRCCandidates(s *discordgo.Session, i *discordgo.InteractionCreate, candidates []service.VRChatUser) {
+	shown := candidates
+	if len(shown) > maxVRCCandidates {
+		shown = shown[:maxVRCCandidates]
+	}
+
+	embeds := make([]*discordgo.MessageEmbed, 0, len(shown))
+	options := make([]discordgo.SelectMenuOption, 0, len(shown))
+	for n, c := range shown {
+		thumb := c.CurrentAvatarThumbnailImageURL
+		if thumb == "" {
+			thumb = c.CurrentAvatarImageURL
+		}
+		embed := &discordgo.MessageEmbed{
+			Title:       fmt.Sprintf("%d. %s", n+1, c.DisplayName),
+			URL:         "https://vrchat.com/home/user/" + c.ID,
+			Description: "`" + c.ID + "`",
+			Color:       0x5865f2,
+		}
+		if thumb != "" {
+			embed.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: thumb}
+		}
+		embeds = append(embeds, embed)
+
+		options = append(options, discordgo.SelectMenuOption{
+			Label:       fmt.Sprintf("%d. %s", n+1, c.DisplayName),
+			Description: c.ID,
+			Value:       c.ID,
+		})
+	}
+
+	msg := "同じ VRChat名のユーザーが複数いる。自分のアカウントを選んでくれ。"
+	if len(candidates) > len(shown) {
+		msg += fmt.Sprintf("（%d人中%d人を表示。見つからなければユーザーID（usr_...）かプロフィールURLで登録してくれ）", len(candidates), len(shown))
+	}
+
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: msg,
+			Embeds:  embeds,
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.SelectMenu{
+							CustomID:    selectWhitelistVRCCandidate,
+							Placeholder: "自分の VRChat アカウントを選ぶ",
+							Options:     options,
+						},
+					},
+				},
+			},
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// 候補の選択: 選ばれた usr_ ID で登録を仕上げ、候補一覧のメッセージを結果で置き換える
+func (r *Router) handleWhitelistCandidateSelect(s *discordgo.Session, i *discordgo.InteractionCreate, userID string, selected []string) {
+	if len(selected) == 0 {
+		return
+	}
+	if !r.checkRegisterRoles(s, i) {
+		return
+	}
+	_, username, avatarURL := extractUserInfo(i)
+
+	// ID での登録になるので、モーダルと同じ確認（他人が使っていないか等）を通る
+	ctx := actorContext(i, service.SourceDiscordModal)
+	created, err := r.WhitelistService.RegisterDiscordVRC(ctx, userID, selected[0])
+
+	r.respondRegisterResult(s, i, ctx, discordgo.InteractionResponseUpdateMessage,
+		userID, username, avatarURL, created, err)
+}
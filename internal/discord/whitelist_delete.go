@@ -0,0 +1,169 @@
+package discord
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// 削除の確認ボタン。custom ID は "<prefix>:<token>"。
+const (
+	btnWhitelistDeleteConfirm = "wl_delete_confirm"
+	btnWhitelistDeleteCancel  = "wl_delete_cancel"
+)
+
+// 確認画面を出してから「本当に削除」を押せる時間
+const deleteConfirmTTL = time.Minute
+
+// 削除確認のワンタイムトークン。メモリに持つだけなので再起動で全部無効になる（それで困らない）。
+// 古い確認画面や、他人の確認画面のボタンでは削除できないようにする。
+type confirmTokens struct {
+	mu     sync.Mutex
+	tokens map[string]confirmToken
+}
+
+type confirmToken struct {
+	userID    string
+	expiresAt time.Time
+}
+
+func newConfirmTokens() *confirmTokens {
+	return &confirmTokens{tokens: make(map[string]confirmToken)}
+}
+
+// userID 用のトークンを発行する。ついでに期限切れのものを掃除する。
+func (c *confirmTokens) issue(userID string) (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for t, v := range c.tokens {
+		if !now.Before(v.expiresAt) {
+			delete(c.tokens, t)
+		}
+	}
+	c.tokens[token] = confirmToken{userID: userID, expiresAt: now.Add(deleteConfirmTTL)}
+	return token, nil
+}
+
+// トークンを使い切る。userID のもので期限内なら true。使えるのは1回だけ。
+func (c *confirmTokens) consume(token, userID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	v, ok := c.tokens[token]
+	if !ok {
+		return false
+	}
+	delete(c.tokens, token)
+	return v.userID == userID && time.Now().Before(v.expiresAt)
+}
+
+// 確認ボタンの custom ID からトークンを取り出す
+func confirmTokenFromCustomID(customID, prefix string) (string, bool) {
+	token, ok := strings.CutPrefix(customID, prefix+":")
+	return token, ok && token != ""
+}
+
+// 「削除」ボタン: すぐには消さず、パネルを確認画面に差し替える
+func (r *Router) handleWhitelistDeleteRequest(s *discordgo.Session, i *discordgo.InteractionCreate, userID string) {
+	ctx := context.Background()
+	link, err := r.WhitelistService.GetDiscordVRC(ctx, userID)
+	if err != nil {
+		log.Printf("GetDiscordVRC internal error: %+v", err)
+	}
+
+	var target string
+	if link != nil {
+		target = fmt.Sprintf("VRChat アカウント「%s」との紐付けを削除して、ホワイトリストから外す。", link.VRCDisplayName)
+	} else if entry := r.waitlistEntry(ctx, userID); entry != nil {
+		// キャンセル待ちに並んでいるだけなら列から外す
+		target = fmt.Sprintf("キャンセル待ち（%d 番目）から外す。並び直すと最後尾になる。", entry.Position)
+	} else {
+		r.respondPanelUpdate(s, i, userID, "ホワイトリストに登録されていない。")
+		return
+	}
+
+	token, err := r.deleteTokens.issue(userID)
+	if err != nil {
+		log.Printf("failed to issue delete token: %+v", err)
+		r.respondPanelUpdate(s, i, userID, "内部エラーで削除の確認を出せなかった。")
+		return
+	}
+	until := time.Now().Add(deleteConfirmTTL)
+
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content: "",
+			Embeds: []*discordgo.MessageEmbed{
+				{
+					Title: "🗑 本当に削除する？",
+					Description: fmt.Sprintf("%s\n<t:%d:R> までに `本当に削除` を押さなければ何もしない。",
+						target, until.Unix()),
+					Color: 0xff5555,
+				},
+			},
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						&discordgo.Button{
+							CustomID: btnWhitelistDeleteConfirm + ":" + token,
+							Label:    "本当に削除",
+							Style:    discordgo.DangerButton,
+						},
+						&discordgo.Button{
+							CustomID: btnWhitelistDeleteCancel + ":" + token,
+							Label:    "キャンセル",
+							Style:    discordgo.SecondaryButton,
+						},
+					},
+				},
+			},
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// 「本当に削除」: トークンが生きていれば handleWhitelistDelete で削除する
+func (r *Router) handleWhitelistDeleteConfirm(s *discordgo.Session, i *discordgo.InteractionCreate, userID, token string) {
+	if !r.deleteTokens.consume(token, userID) {
+		r.respondPanelUpdate(s, i, userID, "確認の期限が切れた。削除するならもう一度 `削除` を押してくれ。")
+		return
+	}
+	r.handleWhitelistDelete(s, i, userID)
+}
+
+// 「キャンセル」: トークンを捨ててパネルに戻す
+func (r *Router) handleWhitelistDeleteCancel(s *discordgo.Session, i *discordgo.InteractionCreate, userID, token string) {
+	r.deleteTokens.consume(token, userID)
+	r.respondPanelUpdate(s, i, userID, "削除をキャンセルした。")
+}
+
+// 元のメッセージを最新のパネルで置き換える
+func (r *Router) respondPanelUpdate(s *discordgo.Session, i *discordgo.InteractionCreate, userID, msg string) {
+	_, username, avatarURL := extractUserInfo(i)
+	embed, components := r.whitelistPanel(context.Background(), userID, username, avatarURL)
+
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    msg,
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: components,
+			Flags:      discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
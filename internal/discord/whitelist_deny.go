@@ -0,0 +1,164 @@
+package discord
+
+import (
+	"backend/internal/service"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// /whitelist-admin denylist で表示する最大件数（Embed の文字数制限があるので）
+const adminDenylistLimit = 25
+
+// /whitelist-admin deny [user:@user] [vrc:野菜ラップ] [reason:...]
+// VRChat アカウントの解決に API を叩くので、先に「考え中」を返してから結果で書き換える。
+func (r *Router) handleWhitelistAdminDeny(
+	s *discordgo.Session,
+	i *discordgo.InteractionCreate,
+	sub *discordgo.ApplicationCommandInteractionDataOption,
+) {
+	var discordID, vrc, reason string
+	if target := optionUser(i, sub, "user"); target != nil {
+		discordID = target.ID
+	}
+	if opt := findOption(sub, "vrc"); opt != nil {
+		vrc = strings.TrimSpace(opt.StringValue())
+	}
+	if opt := findOption(sub, "reason"); opt != nil {
+		reason = strings.TrimSpace(opt.StringValue())
+	}
+	if discordID == "" && vrc == "" {
+		respondEphemeral(s, i, "`user` か `vrc` のどちらかを指定してくれ。")
+		return
+	}
+
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	}); err != nil {
+		log.Printf("failed to defer deny response: %+v", err)
+		return
+	}
+
+	ctx := actorContext(i, service.SourceDiscordCommand)
+	added, err := r.WhitelistService.DenyAccount(ctx, discordID, vrc, reason)
+
+	var msg string
+	switch {
+	case errors.Is(err, service.ErrInvalidArgument):
+		msg = "理由は255文字以内にしてくれ。"
+	case errors.Is(err, service.ErrNoExactMatch), errors.Is(err, service.ErrVRCUserNotFound):
+		msg = "その VRChat ユーザーはいない。"
+	case errors.Is(err, service.ErrMultipleExactMatch):
+		msg = "同じ VRChat名のユーザーが複数いる。ユーザーID（usr_...）かプロフィールURLを指定してくれ。"
+	case errors.Is(err, service.ErrAlreadyExists):
+		msg = "既に登録禁止リストに載っている。`denylist` で確認できる。"
+	case err != nil:
+		log.Printf("DenyAccount internal error: %+v", err)
+		msg = "内部エラーで登録禁止にできなかった。"
+	default:
+		var b strings.Builder
+		b.WriteString("登録禁止リストに追加した:\n")
+		for _, d := range added {
+			fmt.Fprintf(&b, "#%d %s\n", d.ID, formatDenyTarget(d.DiscordUserID, d.VRCUserID))
+		}
+		b.WriteString("今の登録があれば外した。")
+		msg = b.String()
+	}
+
+	if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content:         &msg,
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	}); err != nil {
+		log.Printf("failed to edit deny response: %+v", err)
+	}
+}
+
+// /whitelist-admin undeny id:3
+func (r *Router) handleWhitelistAdminUndeny(
+	s *discordgo.Session,
+	i *discordgo.InteractionCreate,
+	sub *discordgo.ApplicationCommandInteractionDataOption,
+) {
+	var id uint64
+	if opt := findOption(sub, "id"); opt != nil && opt.IntValue() > 0 {
+		id = uint64(opt.IntValue())
+	}
+
+	ctx := actorContext(i, service.SourceDiscordCommand)
+	err := r.WhitelistService.RemoveDeny(ctx, id)
+	switch {
+	case errors.Is(err, service.ErrInvalidArgument):
+		respondEphemeral(s, i, "番号を指定してくれ。")
+	case errors.Is(err, service.ErrNotFound):
+		respondEphemeral(s, i, fmt.Sprintf("#%d は登録禁止リストにない。", id))
+	case err != nil:
+		log.Printf("RemoveDeny internal error: %+v", err)
+		respondEphemeral(s, i, "内部エラーで解除に失敗した。")
+	default:
+		respondEphemeral(s, i, fmt.Sprintf("#%d の登録禁止を解いた。外れた登録は戻らないので、必要なら `restore` してくれ。", id))
+	}
+}
+
+// /whitelist-admin denylist
+func (r *Router) handleWhitelistAdminDenylist(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	entries, err := r.WhitelistService.ListDenied(context.Background())
+	if err != nil {
+		log.Printf("ListDenied internal error: %+v", err)
+		respondEphemeral(s, i, "内部エラーで取得に失敗した。")
+		return
+	}
+	if len(entries) == 0 {
+		respondEphemeral(s, i, "登録禁止リストは空。")
+		return
+	}
+
+	shown := entries
+	if len(shown) > adminDenylistLimit {
+		shown = shown[:adminDenylistLimit]
+	}
+	lines := make([]string, 0, len(shown)+1)
+	for _, d := range shown {
+		line := fmt.Sprintf("`#%d` %s — <t:%d:d>", d.ID, formatDenyTarget(d.DiscordUserID, d.VRCUserID), d.CreatedAt.Unix())
+		if d.Reason != "" {
+			line += "\n　理由: " + truncateRunes(d.Reason, 100)
+		}
+		lines = append(lines, line)
+	}
+	if len(entries) > len(shown) {
+		lines = append(lines, fmt.Sprintf("…ほか %d 件", len(entries)-len(shown)))
+	}
+
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{
+				{
+					Title:       fmt.Sprintf("🚫 登録禁止リスト（%d件）", len(entries)),
+					Description: strings.Join(lines, "\n"),
+					Color:       0xff5555,
+				},
+			},
+			Flags:           discordgo.MessageFlagsEphemeral,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	})
+}
+
+// 「<@id> / VRChat `usr_...`」の形にする
+func formatDenyTarget(discordID, vrcUserID string) string {
+	parts := make([]string, 0, 2)
+	if discordID != "" {
+		parts = append(parts, fmt.Sprintf("<@%s>", discordID))
+	}
+	if vrcUserID != "" {
+		parts = append(parts, fmt.Sprintf("VRChat `%s`", vrcUserID))
+	}
+	return strings.Join(parts, " / ")
+}
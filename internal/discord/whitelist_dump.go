@@ -0,0 +1,67 @@
+package discord
+
+import (
+	"backend/internal/service"
+	"bytes"
+	"context"
+	"log"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// /whitelist-admin export [format:csv|ndjson]
+// 件数が多いと3秒を超えるので、先に「考え中」を返してからファイル付きで書き換える。
+func (r *Router) handleWhitelistAdminExport(
+	s *discordgo.Session,
+	i *discordgo.InteractionCreate,
+	sub *discordgo.ApplicationCommandInteractionDataOption,
+) {
+	format := service.DumpFormatCSV
+	if opt := findOption(sub, "format"); opt != nil {
+		format = opt.StringValue()
+	}
+
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	}); err != nil {
+		log.Printf("failed to defer export response: %+v", err)
+		return
+	}
+
+	// discordgo は multipart の本文をメモリ上で組み立ててから送るので、こちらもバッファに書き切ってから渡す
+	var buf bytes.Buffer
+	if err := r.WhitelistService.DumpWhitelist(context.Background(), &buf, format); err != nil {
+		log.Printf("whitelist export (%s) failed: %+v", format, err)
+		failed := "エクスポートに失敗した。時間をおいて試してくれ。"
+		_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &failed})
+		return
+	}
+
+	msg := "ホワイトリストの全件をエクスポートした。"
+	_, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &msg,
+		Files: []*discordgo.File{
+			{
+				Name:        "whitelist-" + time.Now().In(jst).Format("20060102-150405") + "." + format,
+				ContentType: dumpContentType(format),
+				Reader:      &buf,
+			},
+		},
+	})
+	if err != nil {
+		log.Printf("whitelist export (%s) upload failed: %+v", format, err)
+		failed := "エクスポートに失敗した。時間をおいて試してくれ。"
+		_, _ = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &failed})
+	}
+}
+
+func dumpContentType(format string) string {
+	if format == service.DumpFormatNDJSON {
+		return "application/x-ndjson"
+	}
+	return "text/csv"
+}
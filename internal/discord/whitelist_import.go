@@ -0,0 +1,166 @@
+package discord
+
+import (
+	"backend/internal/models"
+	"backend/internal/service"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// 添付 CSV の最大サイズ
+const maxImportFileSize = 1 << 20
+
+// /whitelist-admin import file:<csv> [dry_run:true]
+// VRChat API を1行ずつ叩くので3秒以内に返せない。先に「考え中」を返してから結果で書き換える。
+func (r *Router) handleWhitelistAdminImport(
+	s *discordgo.Session,
+	i *discordgo.InteractionCreate,
+	sub *discordgo.ApplicationCommandInteractionDataOption,
+) {
+	attachment := optionAttachment(i, sub, "file")
+	if attachment == nil {
+		respondEphemeral(s, i, "CSVファイルを添付してくれ。")
+		return
+	}
+	if attachment.Size > maxImportFileSize {
+		respondEphemeral(s, i, "CSVファイルが大きすぎる（1MBまで）。")
+		return
+	}
+	dryRun := false
+	if opt := findOption(sub, "dry_run"); opt != nil {
+		dryRun = opt.BoolValue()
+	}
+
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	}); err != nil {
+		log.Printf("failed to defer import response: %+v", err)
+		return
+	}
+
+	edit := func(msg string, files ...*discordgo.File) {
+		if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content:         &msg,
+			Files:           files,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		}); err != nil {
+			log.Printf("failed to edit import response: %+v", err)
+		}
+	}
+
+	body, err := downloadAttachment(attachment.URL)
+	if err != nil {
+		log.Printf("whitelist import: download failed: %+v", err)
+		edit("添付ファイルを取得できなかった。")
+		return
+	}
+
+	ctx := actorContext(i, service.SourceDiscordCommand)
+	report, err := r.WhitelistService.ImportWhitelistCSV(ctx, bytes.NewReader(body), dryRun)
+	switch {
+	case errors.Is(err, service.ErrInvalidArgument):
+		edit("CSVを読めなかった。1行目のヘッダーに Discord ID と VRChat名の列（名前に「discord」「vrchat」を含む）が必要で、500行まで。")
+		return
+	case err != nil:
+		log.Printf("ImportWhitelistCSV internal error: %+v", err)
+		edit("内部エラーでインポートに失敗した。")
+		return
+	}
+
+	edit(formatImportSummary(report), &discordgo.File{
+		Name:        "whitelist_import_report.csv",
+		ContentType: "text/csv",
+		Reader:      bytes.NewReader(importReportCSV(report)),
+	})
+}
+
+// 添付ファイルを Discord の CDN から取ってくる
+func downloadAttachment(url string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("attachment download: status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxImportFileSize))
+}
+
+// 結果の件数をまとめた1メッセージ分のテキスト
+func formatImportSummary(report *models.WhitelistImportReport) string {
+	title := "📥 インポートした。"
+	if report.DryRun {
+		title = "🧪 ドライラン（まだ何も登録していない）。"
+	}
+	labels := []struct{ status, label string }{
+		{models.ImportStatusCreated, "新規"},
+		{models.ImportStatusUpdated, "更新"},
+		{models.ImportStatusNoMatch, "該当なし"},
+		{models.ImportStatusMultipleMatches, "複数該当"},
+		{models.ImportStatusConflict, "競合"},
+		{models.ImportStatusInvalid, "不正"},
+		{models.ImportStatusDenied, "登録禁止"},
+		{models.ImportStatusError, "エラー"},
+	}
+	parts := make([]string, 0, len(labels))
+	for _, l := range labels {
+		parts = append(parts, fmt.Sprintf("%s %d", l.label, report.Summary[l.status]))
+	}
+	return fmt.Sprintf("%s 全%d行\n%s\n行ごとの結果は添付のCSVを見てくれ。", title, len(report.Rows), strings.Join(parts, " / "))
+}
+
+// 行ごとの結果を CSV にする
+func importReportCSV(report *models.WhitelistImportReport) []byte {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	_ = w.Write([]string{"line", "discord_user_id", "vrc_display_name", "status", "vrc_user_id", "message"})
+	for _, row := range report.Rows {
+		_ = w.Write([]string{
+			strconv.Itoa(row.Line),
+			row.DiscordUserID,
+			row.VRCDisplayName,
+			row.Status,
+			row.VRCUserID,
+			row.Message,
+		})
+	}
+	w.Flush()
+	return buf.Bytes()
+}
+
+// 添付ファイル型オプションを解決済みの添付ファイルとして取り出す
+func optionAttachment(i *discordgo.InteractionCreate, sub *discordgo.ApplicationCommandInteractionDataOption, name string) *discordgo.MessageAttachment {
+	opt := findOption(sub, name)
+	if opt == nil {
+		return nil
+	}
+	id, _ := opt.Value.(string)
+	res := i.ApplicationCommandData().Resolved
+	if id == "" || res == nil {
+		return nil
+	}
+	return res.Attachments[id]
+}
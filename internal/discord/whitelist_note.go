@@ -0,0 +1,139 @@
+package discord
+
+import (
+	"backend/internal/service"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// 管理者用メモのモーダル。custom ID は "<modalWhitelistNote>:<対象の Discord ID>"。
+const (
+	modalWhitelistNote = "wl_modal_note"
+	modalInputNote     = "wl_modal_input_note"
+)
+
+// /whitelist-admin note user:@user
+// 今のメモを入れた状態でモーダルを開く（空にして送ると消える）
+func (r *Router) handleWhitelistAdminNote(
+	s *discordgo.Session,
+	i *discordgo.InteractionCreate,
+	sub *discordgo.ApplicationCommandInteractionDataOption,
+) {
+	target := optionUser(i, sub, "user")
+	if target == nil {
+		respondEphemeral(s, i, "対象ユーザーを指定してくれ。")
+		return
+	}
+
+	link, err := r.WhitelistService.GetDiscordVRC(context.Background(), target.ID)
+	if err != nil {
+		log.Printf("GetDiscordVRC internal error: %+v", err)
+		respondEphemeral(s, i, "内部エラーで登録を取得できなかった。")
+		return
+	}
+	if link == nil {
+		respondEphemeral(s, i, fmt.Sprintf("<@%s> はホワイトリストに登録されていない。", target.ID))
+		return
+	}
+
+	title := "メモを編集"
+	if target.Username != "" {
+		title = target.Username + " さんのメモを編集"
+	}
+
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: modalWhitelistNote + ":" + target.ID,
+			// モーダルのタイトルは45文字まで
+			Title: truncateRunes(title, 45),
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						&discordgo.TextInput{
+							CustomID:    modalInputNote,
+							Label:       "メモ（管理者だけに見える。空にすると消える）",
+							Style:       discordgo.TextInputParagraph,
+							Value:       link.Note,
+							MaxLength:   255,
+							Placeholder: "例: 大会当日は別アカウントで参加予定",
+						},
+					},
+				},
+			},
+		},
+	})
+}
+
+// メモのモーダル送信
+func (r *Router) handleWhitelistNoteSubmit(s *discordgo.Session, i *discordgo.InteractionCreate, targetID string) {
+	// モーダルを開いたあとに権限が外れることもあるので、送信時にも確認する
+	if !isAdmin(i) {
+		respondEphemeral(s, i, "メモを編集できるのは管理者だけ。")
+		return
+	}
+
+	var note string
+	for _, comp := range i.ModalSubmitData().Components {
+		row, ok := comp.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for _, inner := range row.Components {
+			if input, ok := inner.(*discordgo.TextInput); ok && input.CustomID == modalInputNote {
+				note = input.Value
+			}
+		}
+	}
+
+	ctx := actorContext(i, service.SourceDiscordModal)
+	err := r.WhitelistService.SetNote(ctx, targetID, note)
+
+	var msg string
+	switch {
+	case errors.Is(err, service.ErrInvalidArgument):
+		msg = "メモが長すぎる（255文字まで）。"
+	case errors.Is(err, service.ErrNotRegistered):
+		msg = fmt.Sprintf("<@%s> はホワイトリストに登録されていない（削除された？）。", targetID)
+	case err != nil:
+		log.Printf("SetNote internal error: %+v", err)
+		msg = "内部エラーでメモの保存に失敗した。"
+	case strings.TrimSpace(note) == "":
+		msg = fmt.Sprintf("<@%s> のメモを消した。", targetID)
+	default:
+		msg = fmt.Sprintf("<@%s> のメモを保存した。", targetID)
+	}
+
+	// モーダル送信には対象者の情報が付いてこないので引き直す（パネルの名前・アイコン用）
+	target, uerr := s.User(targetID)
+	if uerr != nil {
+		target = &discordgo.User{ID: targetID}
+	}
+	r.respondAdminPanel(s, i, ctx, msg, target)
+}
+
+// 管理者向けのパネルにメモを足す（本人のパネルには出さない）
+func appendNoteField(embed *discordgo.MessageEmbed, note string) {
+	if note == "" {
+		return
+	}
+	embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+		Name: "📝 管理者メモ",
+		// フィールドの値は1024文字まで
+		Value: truncateRunes(note, 1024),
+	})
+}
+
+// 文字数（rune）で切り詰める
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
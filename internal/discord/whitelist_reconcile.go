@@ -0,0 +1,60 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// /whitelist-admin reconcile
+// サーバー全員のロールをホワイトリストに合わせる（手で付け外しされた・Bot が落ちていた間の変更など）。
+// 人数が多いと3秒を超えるので、先に「考え中」を返してから結果で書き換える。
+func (r *Router) handleWhitelistAdminReconcile(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if r.RoleSyncer == nil || r.RoleSyncer.RoleID(i.GuildID) == "" {
+		respondEphemeral(s, i, "このサーバーにはホワイトリスト用のロールが設定されていない（`DISCORD_WHITELISTED_ROLE_IDS`）。")
+		return
+	}
+
+	if err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	}); err != nil {
+		log.Printf("failed to defer reconcile response: %+v", err)
+		return
+	}
+
+	edit := func(msg string) {
+		if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+			Content:         &msg,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		}); err != nil {
+			log.Printf("failed to edit reconcile response: %+v", err)
+		}
+	}
+
+	ctx := context.Background()
+	users, err := r.WhitelistService.ListWhitelist(ctx)
+	if err != nil {
+		log.Printf("ListWhitelist internal error: %+v", err)
+		edit("内部エラーでホワイトリストを取得できなかった。")
+		return
+	}
+	whitelisted := make(map[string]bool, len(users))
+	for _, u := range users {
+		whitelisted[u.DiscordUserID] = true
+	}
+
+	added, removed, err := r.RoleSyncer.Reconcile(ctx, i.GuildID, whitelisted)
+	if err != nil {
+		log.Printf("role reconcile failed: %+v", err)
+		edit(fmt.Sprintf("途中で失敗した（付けた: %d人 / 外した: %d人）。Bot の権限とロールの順番、Server Members Intent を確認してくれ。", added, removed))
+		return
+	}
+
+	roleID := r.RoleSyncer.RoleID(i.GuildID)
+	edit(fmt.Sprintf("<@&%s> をホワイトリストに合わせた。付けた: %d人 / 外した: %d人", roleID, added, removed))
+}
@@ -0,0 +1,39 @@
+package discord
+
+import (
+	"backend/internal/service"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// 登録・変更の連打制限に引っかかったときの文言。いつから試せるかは Discord のタイムスタンプで見せる。
+func rateLimitMessage(e *service.RateLimitError) string {
+	if e.Daily {
+		return fmt.Sprintf("1日に VRChat アカウントを変えられる回数の上限に達した。<t:%d:R> からまた変更できる。", e.RetryAt.Unix())
+	}
+	return fmt.Sprintf("続けて登録・変更はできない。<t:%d:R> にもう一度試してくれ。", e.RetryAt.Unix())
+}
+
+// 今すぐ登録・変更できるか。だめなら本人にだけ理由を返して false。
+// モーダルを開く前に見ておけば、入力してから断られることが減る。サーバー管理者は制限しない。
+func (r *Router) checkRegisterRateLimit(s *discordgo.Session, i *discordgo.InteractionCreate) bool {
+	if isAdmin(i) {
+		return true
+	}
+	err := r.WhitelistService.CheckRegisterRateLimit(context.Background(), extractUserID(i))
+	var limited *service.RateLimitError
+	switch {
+	case err == nil:
+		return true
+	case errors.As(err, &limited):
+		respondEphemeral(s, i, rateLimitMessage(limited))
+	default:
+		log.Printf("CheckRegisterRateLimit internal error: %+v", err)
+		respondEphemeral(s, i, "内部エラーで登録できるか確認できなかった。時間をおいて試してくれ。")
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package discord
+
+import (
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// 本人による登録に必要なロールを持っているか。持っていなければ本人にだけ理由を返して false。
+// 登録ボタン・モーダル送信・候補の選択のそれぞれで確認する（パネルを開いたあとにロールが外れることもあるため）。
+func (r *Router) checkRegisterRoles(s *discordgo.Session, i *discordgo.InteractionCreate) bool {
+	if r.RoleGate == nil || !r.RoleGate.Enabled() {
+		return true
+	}
+	// DM からだとロールが分からない
+	if i.Member == nil {
+		respondEphemeral(s, i, "ホワイトリストの登録はサーバー内で行ってくれ。")
+		return false
+	}
+	if r.RoleGate.AllowMember(i.GuildID, i.Member.Roles) {
+		return true
+	}
+
+	required := r.RoleGate.RequiredRoles(i.GuildID)
+	mentions := make([]string, 0, len(required))
+	for _, roleID := range required {
+		mentions = append(mentions, "<@&"+roleID+">")
+	}
+	respondEphemeral(s, i, "ホワイトリストに登録できるのは "+strings.Join(mentions, " / ")+
+		" のどれかのロールを持っている人だけ。ロールが付いていないなら運営に聞いてくれ。")
+	return false
+}
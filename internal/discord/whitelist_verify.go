@@ -0,0 +1,55 @@
+package discord
+
+import (
+	"backend/internal/service"
+	"errors"
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// 「確認」ボタン: VRChat の bio に確認コードが書かれているか見て、本人確認を済ませる
+func (r *Router) handleWhitelistVerify(s *discordgo.Session, i *discordgo.InteractionCreate, userID string) {
+	_, username, avatarURL := extractUserInfo(i)
+
+	ctx := actorContext(i, service.SourceDiscordButton)
+	err := r.WhitelistService.VerifyDiscordVRC(ctx, userID)
+
+	var msg string
+	switch {
+	case errors.Is(err, service.ErrNotRegistered):
+		msg = "先に VRChat 名を登録してくれ。"
+	case errors.Is(err, service.ErrVerifyCodeNotFound):
+		msg = "VRChat のプロフィール（bio）に確認コードが見つからなかった。保存されているか確認してもう一度押してくれ（反映まで少しかかることがある）。"
+	case errors.Is(err, service.ErrVerifyExpired):
+		msg = "確認コードの期限が切れた。もう一度 `登録 / 更新` からやり直してくれ。"
+	case errors.Is(err, service.ErrVRCUserNotFound):
+		msg = "登録した VRChat アカウントが見つからなかった。もう一度 `登録 / 更新` からやり直してくれ。"
+	case errors.Is(err, service.ErrAlreadyExists):
+		msg = "その VRChat アカウントは別の Discord ユーザーが先に本人確認を済ませている。"
+	case err != nil:
+		log.Printf("VerifyDiscordVRC internal error: %+v", err)
+		msg = "内部エラーで確認に失敗した。時間をおいて試してくれ。"
+	default:
+		msg = "本人確認が済んだ。ホワイトリストに登録した。bio の確認コードは消してOK。"
+	}
+
+	embed, components := r.whitelistPanel(ctx, userID, username, avatarURL)
+
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{
+			Content:    msg,
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: components,
+			Flags:      discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	// 本人確認が済んで初めてホワイトリストに載るので、ここで公開メッセージを流す
+	if err == nil {
+		if link, gerr := r.WhitelistService.GetDiscordVRC(ctx, userID); gerr == nil && link != nil {
+			r.announceWhitelist(s, i, userID, link.VRCDisplayName, embed, true)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package discord
+
+import (
+	"backend/internal/models"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// /whitelist-admin waitlist で表示する最大件数（Embed の文字数制限があるので）
+const adminWaitlistLimit = 25
+
+// キャンセル待ちの順番。並んでいない・取れなければ nil。
+func (r *Router) waitlistEntry(ctx context.Context, discordID string) *models.WhitelistWaitlistEntry {
+	entry, err := r.WhitelistService.GetWaitlistEntry(ctx, discordID)
+	if err != nil {
+		log.Printf("GetWaitlistEntry internal error: %+v", err)
+		return nil
+	}
+	return entry
+}
+
+// パネルの「キャンセル待ち」欄。並んでいなければ nil。
+func (r *Router) waitlistField(ctx context.Context, discordID string) *discordgo.MessageEmbedField {
+	entry := r.waitlistEntry(ctx, discordID)
+	if entry == nil {
+		return nil
+	}
+	return &discordgo.MessageEmbedField{
+		Name: "キャンセル待ち",
+		Value: fmt.Sprintf("🕒 %d 番目（VRChat: 「%s」）\n空きが出たら順番に自動で登録して DM で知らせる。",
+			entry.Position, entry.VRCDisplayName),
+	}
+}
+
+// /whitelist-admin waitlist
+func (r *Router) handleWhitelistAdminWaitlist(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	ctx := context.Background()
+
+	capacity, err := r.WhitelistService.GetCapacity(ctx)
+	if err != nil {
+		log.Printf("GetCapacity internal error: %+v", err)
+		respondEphemeral(s, i, "内部エラーで取得に失敗した。")
+		return
+	}
+	entries, err := r.WhitelistService.ListWaitlist(ctx)
+	if err != nil {
+		log.Printf("ListWaitlist internal error: %+v", err)
+		respondEphemeral(s, i, "内部エラーで取得に失敗した。")
+		return
+	}
+
+	capacityValue := "定員なし（`WHITELIST_CAPACITY`）"
+	if capacity.Capacity > 0 {
+		capacityValue = fmt.Sprintf("%d / %d 人", capacity.Registered, capacity.Capacity)
+	}
+
+	description := "キャンセル待ちはいない。"
+	if len(entries) > 0 {
+		shown := entries
+		if len(shown) > adminWaitlistLimit {
+			shown = shown[:adminWaitlistLimit]
+		}
+		lines := make([]string, 0, len(shown)+1)
+		for _, e := range shown {
+			lines = append(lines, fmt.Sprintf("`%d.` <@%s> — %s（`%s`） <t:%d:R>",
+				e.Position, e.DiscordUserID, e.VRCDisplayName, e.VRCUserID, e.CreatedAt.Unix()))
+		}
+		if len(entries) > len(shown) {
+			lines = append(lines, fmt.Sprintf("…ほか %d 人", len(entries)-len(shown)))
+		}
+		description = strings.Join(lines, "\n")
+	}
+
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{
+				{
+					Title:       fmt.Sprintf("🕒 キャンセル待ち（%d人）", len(entries)),
+					Description: description,
+					Color:       0xffb020,
+					Fields: []*discordgo.MessageEmbedField{
+						{Name: "登録数", Value: capacityValue},
+					},
+				},
+			},
+			Flags:           discordgo.MessageFlagsEphemeral,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	})
+}
@@ -0,0 +1,274 @@
+package discord
+
+import (
+	"backend/internal/models"
+	"backend/internal/service"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// パネルに出す本人による登録の受付状況
+type registrationStatus struct {
+	window   models.RegistrationWindow
+	override bool // 管理者に個別に許可されている
+	now      time.Time
+}
+
+// 受付期間と個別の許可を引く。取れなければ受付中として扱う（登録時に service 側でもう一度見る）。
+func (r *Router) registrationStatus(ctx context.Context, discordID string) registrationStatus {
+	st := registrationStatus{now: time.Now()}
+
+	w, err := r.WhitelistService.GetRegistrationWindow(ctx, "")
+	if err != nil {
+		log.Printf("GetRegistrationWindow internal error: %+v", err)
+		return st
+	}
+	st.window = *w
+
+	if st.window.Limited() {
+		st.override, err = r.WhitelistService.HasRegistrationOverride(ctx, discordID)
+		if err != nil {
+			log.Printf("HasRegistrationOverride internal error: %+v", err)
+		}
+	}
+	return st
+}
+
+func (st registrationStatus) open() bool {
+	return st.override || st.window.IsOpen(st.now)
+}
+
+// 「受付」欄。受付期間が設定されていなければ nil。
+func (st registrationStatus) embedField() *discordgo.MessageEmbedField {
+	if !st.window.Limited() {
+		return nil
+	}
+
+	var value string
+	switch {
+	case st.window.IsOpen(st.now):
+		value = "🟢 受付中"
+		if st.window.ClosesAt != nil {
+			value += fmt.Sprintf("（<t:%d:F> まで）", st.window.ClosesAt.Unix())
+		}
+	case st.window.OpensAt != nil && st.now.Before(*st.window.OpensAt):
+		value = fmt.Sprintf("⛔ 受付終了（<t:%d:F> から受付開始）", st.window.OpensAt.Unix())
+	default:
+		value = "⛔ 受付終了"
+	}
+	if st.override && !st.window.IsOpen(st.now) {
+		value += "\n管理者の許可があるので、受付期間外でも登録・変更できる。"
+	}
+
+	return &discordgo.MessageEmbedField{
+		Name:  "受付",
+		Value: value,
+	}
+}
+
+// 受付期間内か（個別に許可されているか）。だめなら本人にだけ理由を返して false。
+// ボタンを押せなくしていても、受付終了前に開いたパネルからは押せるのでここでも確認する。
+func (r *Router) checkRegistrationWindow(s *discordgo.Session, i *discordgo.InteractionCreate) bool {
+	err := r.WhitelistService.CheckRegistrationOpen(context.Background(), extractUserID(i), "")
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, service.ErrRegistrationClosed):
+		respondEphemeral(s, i, "今はホワイトリストの受付期間外。`再表示` で受付期間を確認できる。")
+	default:
+		log.Printf("CheckRegistrationOpen internal error: %+v", err)
+		respondEphemeral(s, i, "内部エラーで受付期間を確認できなかった。時間をおいて試してくれ。")
+	}
+	return false
+}
+
+// /whitelist-admin window [list] [opens_at] [closes_at] [clear]
+// 日時を何も指定しなければ今の設定を表示する。指定しなかった側はそのまま残す。
+func (r *Router) handleWhitelistAdminWindow(
+	s *discordgo.Session,
+	i *discordgo.InteractionCreate,
+	sub *discordgo.ApplicationCommandInteractionDataOption,
+) {
+	var listName string
+	if opt := findOption(sub, "list"); opt != nil {
+		listName = strings.TrimSpace(opt.StringValue())
+	}
+	clear := false
+	if opt := findOption(sub, "clear"); opt != nil {
+		clear = opt.BoolValue()
+	}
+
+	var opensAt, closesAt *time.Time
+	for _, f := range []struct {
+		name string
+		dst  **time.Time
+	}{
+		{"opens_at", &opensAt},
+		{"closes_at", &closesAt},
+	} {
+		opt := findOption(sub, f.name)
+		if opt == nil {
+			continue
+		}
+		t, err := parseAdminTime(opt.StringValue())
+		if err != nil {
+			respondEphemeral(s, i, "日時の形式が不正。`2026-11-01 23:59`（日本時間）の形で入力してくれ。")
+			return
+		}
+		*f.dst = &t
+	}
+
+	ctx := actorContext(i, service.SourceDiscordCommand)
+	w, err := r.WhitelistService.GetRegistrationWindow(ctx, listName)
+	if err != nil {
+		respondEphemeral(s, i, windowErrorMessage(err, listName))
+		return
+	}
+
+	if !clear && opensAt == nil && closesAt == nil {
+		r.respondWindowSummary(s, i, ctx, listName, *w)
+		return
+	}
+
+	next := *w
+	if clear {
+		next = models.RegistrationWindow{}
+	}
+	if opensAt != nil {
+		next.OpensAt = opensAt
+	}
+	if closesAt != nil {
+		next.ClosesAt = closesAt
+	}
+
+	if err := r.WhitelistService.SetRegistrationWindow(ctx, listName, next); err != nil {
+		respondEphemeral(s, i, windowErrorMessage(err, listName))
+		return
+	}
+	r.respondWindowSummary(s, i, ctx, listName, next)
+}
+
+// /whitelist-admin window-override user:@user [revoke]
+func (r *Router) handleWhitelistAdminWindowOverride(
+	s *discordgo.Session,
+	i *discordgo.InteractionCreate,
+	sub *discordgo.ApplicationCommandInteractionDataOption,
+) {
+	target := optionUser(i, sub, "user")
+	if target == nil {
+		respondEphemeral(s, i, "対象ユーザーを指定してくれ。")
+		return
+	}
+	revoke := false
+	if opt := findOption(sub, "revoke"); opt != nil {
+		revoke = opt.BoolValue()
+	}
+
+	ctx := actorContext(i, service.SourceDiscordCommand)
+
+	var (
+		changed bool
+		err     error
+		msg     string
+	)
+	if revoke {
+		changed, err = r.WhitelistService.RevokeRegistrationOverride(ctx, target.ID)
+	} else {
+		changed, err = r.WhitelistService.GrantRegistrationOverride(ctx, target.ID)
+	}
+	switch {
+	case err != nil:
+		log.Printf("registration override internal error: %+v", err)
+		msg = "内部エラーで受付期間の例外を変更できなかった。"
+	case revoke && changed:
+		msg = fmt.Sprintf("<@%s> の受付期間の例外を取り消した。", target.ID)
+	case revoke:
+		msg = fmt.Sprintf("<@%s> には受付期間の例外が付いていない。", target.ID)
+	case changed:
+		msg = fmt.Sprintf("<@%s> は受付期間の外でも登録・リスト参加できるようになった。", target.ID)
+	default:
+		msg = fmt.Sprintf("<@%s> には既に受付期間の例外が付いている。", target.ID)
+	}
+
+	r.respondAdminPanel(s, i, ctx, msg, target)
+}
+
+// 受付期間の設定と、個別に許可されている人を管理者にだけ返す
+func (r *Router) respondWindowSummary(
+	s *discordgo.Session,
+	i *discordgo.InteractionCreate,
+	ctx context.Context,
+	listName string,
+	w models.RegistrationWindow,
+) {
+	title := "🗓 ホワイトリストの受付期間"
+	if listName != "" {
+		title = fmt.Sprintf("🗓 リスト「%s」の参加受付期間", listName)
+	}
+
+	status := "🟢 受付中"
+	if !w.IsOpen(time.Now()) {
+		status = "⛔ 受付終了"
+	}
+
+	fields := []*discordgo.MessageEmbedField{
+		{Name: "状態", Value: status},
+		{Name: "開始", Value: formatWindowTime(w.OpensAt), Inline: true},
+		{Name: "終了", Value: formatWindowTime(w.ClosesAt), Inline: true},
+	}
+
+	overrides, err := r.WhitelistService.ListRegistrationOverrides(ctx)
+	if err != nil {
+		log.Printf("ListRegistrationOverrides internal error: %+v", err)
+	}
+	if len(overrides) > 0 {
+		mentions := make([]string, 0, len(overrides))
+		for _, o := range overrides {
+			mentions = append(mentions, "<@"+o.DiscordUserID+">")
+		}
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:  fmt.Sprintf("個別に許可（%d人）", len(overrides)),
+			Value: truncateRunes(strings.Join(mentions, " "), 1024),
+		})
+	}
+
+	_ = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{
+				{
+					Title:  title,
+					Color:  0x5865f2,
+					Fields: fields,
+				},
+			},
+			Flags:           discordgo.MessageFlagsEphemeral,
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		},
+	})
+}
+
+func formatWindowTime(t *time.Time) string {
+	if t == nil {
+		return "制限なし"
+	}
+	return fmt.Sprintf("<t:%d:F>", t.Unix())
+}
+
+func windowErrorMessage(err error, listName string) string {
+	switch {
+	case errors.Is(err, service.ErrInvalidArgument):
+		return "開始は終了より前にしてくれ。"
+	case errors.Is(err, service.ErrListNotFound):
+		return fmt.Sprintf("リスト「%s」は無い。", listName)
+	default:
+		log.Printf("registration window internal error: %+v", err)
+		return "内部エラーで受付期間を変更できなかった。"
+	}
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// ワールドごとのエクスポート用トークン。
+// Secret は署名用の鍵なので JSON には出さない。
+type ExportToken struct {
+	ID          uint64     `json:"id"`
+	WorldName   string     `json:"world_name"`
+	TokenPrefix string     `json:"token_prefix"`
+	Secret      string     `json:"-"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+	RevokedAt   *time.Time `json:"revoked_at"`
+}
+
+// 発行直後だけ返す。token と secret を平文で見られるのはこの1回だけ。
+type IssuedExportToken struct {
+	ExportToken
+	Token  string `json:"token"`
+	Secret string `json:"secret"`
+}
@@ -1,14 +1,73 @@
-package models
-
-import "time"
-
-type WhitelistUser struct {
-	ID             uint64
-	DiscordUserID  string
-	VRCUserID      string
-	VRCDisplayName string
-	VRCAvatarURL   string
-	Note           string
-	CreatedAt      time.Time
-	UpdatedAt      time.Time
-}
+package models
+
+import "time"
+
+type WhitelistUser struct {
+	ID             uint64     `json:"id"`
+	DiscordUserID  string     `json:"discord_user_id"`
+	VRCUserID      string     `json:"vrc_user_id"`
+	VRCDisplayName string     `json:"vrc_display_name"`
+	VRCAvatarURL   string     `json:"vrc_avatar_url"`
+	Note           string     `json:"note"`         // 管理者用メモ。この型を返す API は管理用トークン必須のものだけにすること
+	ExpiresAt      *time.Time `json:"expires_at"`   // nil なら無期限
+	VerifiedAt     *time.Time `json:"verified_at"`  // nil なら本人確認待ち（エクスポートに出ない）
+	VerifyCode     string     `json:"-"`            // 本人確認用のワンタイムコード。本人にだけ見せる
+	VerifyUntil    *time.Time `json:"verify_until"` // 本人確認の期限
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// 管理画面向け一覧の並び順（どちらも新しい順）
+const (
+	WhitelistSortCreated = "created"
+	WhitelistSortUpdated = "updated"
+)
+
+// 管理画面向け一覧の検索条件。空の項目は絞り込まない。
+type WhitelistUserFilter struct {
+	DiscordUserID  string
+	VRCUserID      string
+	VRCDisplayName string // 部分一致（大文字小文字を区別しない）
+	Sort           string // WhitelistSortCreated / WhitelistSortUpdated。空なら created
+	Cursor         string // 前ページの NextCursor。空なら先頭から
+	Limit          int
+}
+
+// 一覧の続きの位置。並び順の時刻と id の組で、この位置より後ろから取る。
+type WhitelistUserCursor struct {
+	At time.Time
+	ID uint64
+}
+
+// 一覧の1ページ分。NextCursor が空なら最後のページ。
+type WhitelistUserPage struct {
+	Items      []WhitelistUser `json:"items"`
+	NextCursor string          `json:"next_cursor"`
+}
+
+// VRChat 表示名の変更履歴1件（定期再同期で検出したもの）
+type WhitelistNameChange struct {
+	ID             uint64    `json:"id"`
+	DiscordUserID  string    `json:"discord_user_id"`
+	VRCUserID      string    `json:"vrc_user_id"`
+	OldDisplayName string    `json:"old_display_name"`
+	NewDisplayName string    `json:"new_display_name"`
+	ChangedAt      time.Time `json:"changed_at"`
+}
+
+// ホワイトリスト全体の変更カウンタ。エクスポートの ETag / Last-Modified に使う。
+type WhitelistVersion struct {
+	Version   int64
+	UpdatedAt time.Time
+}
+
+// 名前付きリスト（performers / judges / staff / vip など）
+type WhitelistList struct {
+	ID          uint64 `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	SelfService bool   `json:"self_service"`
+	// /whitelist パネルから参加できる期間
+	RegistrationWindow RegistrationWindow `json:"registration_window"`
+	CreatedAt          time.Time          `json:"created_at"`
+}
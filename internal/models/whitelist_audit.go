@@ -0,0 +1,32 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ホワイトリスト変更の監査ログ1件
+type WhitelistAudit struct {
+	ID            uint64          `json:"id"`
+	Action        string          `json:"action"`
+	DiscordUserID string          `json:"discord_user_id"`
+	Actor         string          `json:"actor"`
+	Source        string          `json:"source"`
+	Before        json.RawMessage `json:"before"`
+	After         json.RawMessage `json:"after"`
+	RequestID     string          `json:"request_id"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// 監査ログの検索条件。BeforeID より小さい id を新しい順に Limit 件。
+type WhitelistAuditFilter struct {
+	DiscordUserID string
+	BeforeID      uint64
+	Limit         int
+}
+
+// 監査ログの1ページ分。続きが無ければ NextBeforeID は 0。
+type WhitelistAuditPage struct {
+	Items        []WhitelistAudit `json:"items"`
+	NextBeforeID uint64           `json:"next_before_id"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// 登録禁止リストの1件。DiscordUserID / VRCUserID の空欄は「その種類では縛らない」。
+type WhitelistDeny struct {
+	ID            uint64    `json:"id"`
+	DiscordUserID string    `json:"discord_user_id,omitempty"`
+	VRCUserID     string    `json:"vrc_user_id,omitempty"`
+	Reason        string    `json:"reason"`
+	AddedBy       string    `json:"added_by"`
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// CSV 一括インポートの1行ごとの結果
+const (
+	ImportStatusCreated         = "created"
+	ImportStatusUpdated         = "updated"
+	ImportStatusNoMatch         = "no_match"
+	ImportStatusMultipleMatches = "multiple_matches"
+	ImportStatusConflict        = "conflict" // その VRChat アカウントは別の Discord ユーザーが使用中
+	ImportStatusInvalid         = "invalid"  // Discord ID か VRChat 名が空、Discord ID が数字の ID ではない
+	ImportStatusDenied          = "denied"   // 登録禁止リストに載っている
+	ImportStatusError           = "error"    // VRChat API / DB の内部エラー
+)
+
+// CSV 1行分の結果。Line はヘッダーを1行目とした行番号。
+type WhitelistImportRow struct {
+	Line           int    `json:"line"`
+	DiscordUserID  string `json:"discord_user_id"`
+	VRCDisplayName string `json:"vrc_display_name"`
+	Status         string `json:"status"`
+	VRCUserID      string `json:"vrc_user_id,omitempty"` // 見つかった VRChat ユーザー
+	Message        string `json:"message,omitempty"`
+}
+
+// CSV 一括インポートの結果。DryRun なら何も書き込まれていない。
+type WhitelistImportReport struct {
+	DryRun  bool                 `json:"dry_run"`
+	Rows    []WhitelistImportRow `json:"rows"`
+	Summary map[string]int       `json:"summary"` // Status ごとの件数
+}
+
+// CSV 一括インポートのジョブの状態
+const (
+	ImportJobRunning = "running"
+	ImportJobDone    = "done"
+	ImportJobFailed  = "failed"
+)
+
+// バックグラウンドで進める CSV 一括インポート。Report は終わってから入る。
+type WhitelistImportJob struct {
+	ID         string                 `json:"id"`
+	Status     string                 `json:"status"` // ImportJob* のどれか
+	DryRun     bool                   `json:"dry_run"`
+	Error      string                 `json:"error,omitempty"`
+	Report     *WhitelistImportReport `json:"report,omitempty"`
+	StartedAt  time.Time              `json:"started_at"`
+	FinishedAt *time.Time             `json:"finished_at,omitempty"`
+}
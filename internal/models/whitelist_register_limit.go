@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// 本人による登録・変更の回数制限の状態（Discord ユーザーごと）
+type RegisterLimit struct {
+	DiscordUserID string    `json:"discord_user_id"`
+	LastAttemptAt time.Time `json:"last_attempt_at"`
+	// ChangesSince から 1日のあいだに VRChat アカウントを変えた回数
+	ChangesSince time.Time `json:"changes_since"`
+	ChangeCount  int       `json:"change_count"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// キャンセル待ちの1件。Position は 1 始まりの順番（読み出すときに数える）。
+type WhitelistWaitlistEntry struct {
+	ID             uint64    `json:"id"`
+	DiscordUserID  string    `json:"discord_user_id"`
+	VRCUserID      string    `json:"vrc_user_id"`
+	VRCDisplayName string    `json:"vrc_display_name"`
+	VRCAvatarURL   string    `json:"vrc_avatar_url"`
+	Position       int       `json:"position"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// 定員と埋まり具合。Capacity が 0 なら定員なし。
+type WhitelistCapacity struct {
+	Capacity   int `json:"capacity"`
+	Registered int `json:"registered"`
+	Waiting    int `json:"waiting"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// 登録の受付期間。OpensAt / ClosesAt の nil は「その側は制限なし」。
+type RegistrationWindow struct {
+	OpensAt  *time.Time `json:"opens_at"`
+	ClosesAt *time.Time `json:"closes_at"`
+}
+
+// 受付期間が設定されているか（両方 nil なら常に受付中）
+func (w RegistrationWindow) Limited() bool {
+	return w.OpensAt != nil || w.ClosesAt != nil
+}
+
+// now が受付期間内か。ClosesAt ちょうどは受付終了。
+func (w RegistrationWindow) IsOpen(now time.Time) bool {
+	if w.OpensAt != nil && now.Before(*w.OpensAt) {
+		return false
+	}
+	if w.ClosesAt != nil && !now.Before(*w.ClosesAt) {
+		return false
+	}
+	return true
+}
+
+// 受付期間の外でも登録できるよう管理者が個別に許可した人
+type RegistrationOverride struct {
+	DiscordUserID string    `json:"discord_user_id"`
+	GrantedBy     string    `json:"granted_by"`
+	CreatedAt     time.Time `json:"created_at"`
+}
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// UNIQUE 制約に引っかかったとき
+var ErrDuplicate = errors.New("duplicate key")
+
+// PostgreSQL の unique_violation (23505) か
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"backend/internal/models"
+	"context"
+	"database/sql"
+)
+
+type ExportTokenRepository interface {
+	Create(ctx context.Context, t *models.ExportToken, tokenHash string) error
+	GetByTokenHash(ctx context.Context, tokenHash string) (*models.ExportToken, error)
+	List(ctx context.Context) ([]models.ExportToken, error)
+	Revoke(ctx context.Context, id uint64) (bool, error)
+	TouchLastUsed(ctx context.Context, id uint64) error
+}
+
+type exportTokenRepository struct {
+	db *sql.DB
+}
+
+func NewExportTokenRepository(db *sql.DB) ExportTokenRepository {
+	return &exportTokenRepository{db: db}
+}
+
+// 作成。ID と CreatedAt は DB 側で振ったものを t に書き戻す。
+func (r *exportTokenRepository) Create(ctx context.Context, t *models.ExportToken, tokenHash string) error {
+	const q = `
+		INSERT INTO world_export_tokens (
+			world_name,
+			token_hash,
+			token_prefix,
+			secret
+		) VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at;
+	`
+	return r.db.QueryRowContext(ctx, q,
+		t.WorldName,
+		tokenHash,
+		t.TokenPrefix,
+		t.Secret,
+	).Scan(&t.ID, &t.CreatedAt)
+}
+
+// 失効済みも含めて返す。失効しているかは呼び出し側で RevokedAt を見る。
+func (r *exportTokenRepository) GetByTokenHash(ctx context.Context, tokenHash string) (*models.ExportToken, error) {
+	const q = `
+		SELECT
+			id,
+			world_name,
+			token_prefix,
+			secret,
+			created_at,
+			last_used_at,
+			revoked_at
+		FROM world_export_tokens
+		WHERE token_hash = $1
+		LIMIT 1;
+	`
+	row := r.db.QueryRowContext(ctx, q, tokenHash)
+
+	var t models.ExportToken
+	if err := row.Scan(
+		&t.ID,
+		&t.WorldName,
+		&t.TokenPrefix,
+		&t.Secret,
+		&t.CreatedAt,
+		&t.LastUsedAt,
+		&t.RevokedAt,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *exportTokenRepository) List(ctx context.Context) ([]models.ExportToken, error) {
+	const q = `
+		SELECT
+			id,
+			world_name,
+			token_prefix,
+			secret,
+			created_at,
+			last_used_at,
+			revoked_at
+		FROM world_export_tokens
+		ORDER BY id ASC;
+	`
+	rows, err := r.db.QueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := make([]models.ExportToken, 0)
+	for rows.Next() {
+		var t models.ExportToken
+		if err := rows.Scan(
+			&t.ID,
+			&t.WorldName,
+			&t.TokenPrefix,
+			&t.Secret,
+			&t.CreatedAt,
+			&t.LastUsedAt,
+			&t.RevokedAt,
+		); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// 失効。対象が無い or 既に失効済みなら false。
+func (r *exportTokenRepository) Revoke(ctx context.Context, id uint64) (bool, error) {
+	const q = `
+		UPDATE world_export_tokens
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND revoked_at IS NULL;
+	`
+	res, err := r.db.ExecContext(ctx, q, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (r *exportTokenRepository) TouchLastUsed(ctx context.Context, id uint64) error {
+	const q = `UPDATE world_export_tokens SET last_used_at = CURRENT_TIMESTAMP WHERE id = $1`
+	_, err := r.db.ExecContext(ctx, q, id)
+	return err
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// 複数のリポジトリにまたがる書き込み（本体の変更と監査ログなど）を1つのトランザクションにまとめる。
+type Transactor interface {
+	// fn を1つのトランザクションで実行する。fn がエラーを返せばロールバックする。
+	// fn に渡す ctx でリポジトリを呼べば同じトランザクションに乗る。既にトランザクション中ならそれに乗る。
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type transactor struct {
+	db *sql.DB
+}
+
+func NewTransactor(db *sql.DB) Transactor {
+	return &transactor{db: db}
+}
+
+type txKey struct{}
+
+func (t *transactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// *sql.DB と *sql.Tx の共通部分
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// ctx にトランザクションが載っていればそれ、無ければ db で実行する
+func conn(ctx context.Context, db *sql.DB) dbtx {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// リポジトリの中で複数の文をまとめるときのトランザクション。
+// ctx に外側のトランザクションが載っていればそれに乗り、Commit / Rollback は外側に任せる。
+type scopedTx struct {
+	dbtx
+	tx *sql.Tx // 自分で始めたときだけ
+}
+
+func beginTx(ctx context.Context, db *sql.DB) (*scopedTx, error) {
+	if tx, ok := ctx.Value(txKey{}).(*sql.Tx); ok {
+		return &scopedTx{dbtx: tx}, nil
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &scopedTx{dbtx: tx, tx: tx}, nil
+}
+
+func (t *scopedTx) Commit() error {
+	if t.tx == nil {
+		return nil
+	}
+	return t.tx.Commit()
+}
+
+func (t *scopedTx) Rollback() error {
+	if t.tx == nil {
+		return nil
+	}
+	return t.tx.Rollback()
+}
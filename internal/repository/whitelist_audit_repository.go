@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"backend/internal/models"
+	"context"
+	"database/sql"
+)
+
+// 監査ログは追記と参照だけ。更新・削除のメソッドは作らない。
+type WhitelistAuditRepository interface {
+	Append(ctx context.Context, a *models.WhitelistAudit) error
+	List(ctx context.Context, f models.WhitelistAuditFilter) ([]models.WhitelistAudit, error)
+}
+
+type whitelistAuditRepository struct {
+	db *sql.DB
+}
+
+func NewWhitelistAuditRepository(db *sql.DB) WhitelistAuditRepository {
+	return &whitelistAuditRepository{db: db}
+}
+
+// 追記。ID と CreatedAt は DB 側で振ったものを a に書き戻す。
+func (r *whitelistAuditRepository) Append(ctx context.Context, a *models.WhitelistAudit) error {
+	const q = `
+		INSERT INTO whitelist_audit (
+			action,
+			discord_user_id,
+			actor,
+			source,
+			before_value,
+			after_value,
+			request_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id, created_at;
+	`
+	return conn(ctx, r.db).QueryRowContext(ctx, q,
+		a.Action,
+		a.DiscordUserID,
+		a.Actor,
+		a.Source,
+		nullableJSON(a.Before),
+		nullableJSON(a.After),
+		a.RequestID,
+	).Scan(&a.ID, &a.CreatedAt)
+}
+
+// 新しい順。BeforeID が 0 なら先頭から。
+func (r *whitelistAuditRepository) List(ctx context.Context, f models.WhitelistAuditFilter) ([]models.WhitelistAudit, error) {
+	const q = `
+		SELECT
+			id,
+			action,
+			discord_user_id,
+			actor,
+			source,
+			before_value,
+			after_value,
+			request_id,
+			created_at
+		FROM whitelist_audit
+		WHERE ($1::text = '' OR discord_user_id = $1::text)
+		  AND ($2::bigint = 0 OR id < $2::bigint)
+		ORDER BY id DESC
+		LIMIT $3;
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, q, f.DiscordUserID, int64(f.BeforeID), f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]models.WhitelistAudit, 0)
+	for rows.Next() {
+		var (
+			a             models.WhitelistAudit
+			before, after []byte
+		)
+		if err := rows.Scan(
+			&a.ID,
+			&a.Action,
+			&a.DiscordUserID,
+			&a.Actor,
+			&a.Source,
+			&before,
+			&after,
+			&a.RequestID,
+			&a.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		a.Before = before
+		a.After = after
+		entries = append(entries, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// 空の JSON は NULL として保存する
+func nullableJSON(b []byte) any {
+	if len(b) == 0 {
+		return nil
+	}
+	return string(b)
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"backend/internal/models"
+	"context"
+	"database/sql"
+)
+
+type WhitelistDenyRepository interface {
+	Add(ctx context.Context, d *models.WhitelistDeny) (bool, error)
+	Find(ctx context.Context, discordID, vrcUserID string) (*models.WhitelistDeny, error)
+	List(ctx context.Context) ([]models.WhitelistDeny, error)
+	Delete(ctx context.Context, id uint64) (*models.WhitelistDeny, error)
+}
+
+type whitelistDenyRepository struct {
+	db *sql.DB
+}
+
+func NewWhitelistDenyRepository(db *sql.DB) WhitelistDenyRepository {
+	return &whitelistDenyRepository{db: db}
+}
+
+const whitelistDenyColumns = `id, discord_user_id, vrc_user_id, reason, added_by, created_at`
+
+// 追加。ID と CreatedAt は DB 側で振ったものを d に書き戻す。
+// 同じ Discord ID / VRChat ID が既に載っていれば何もせず false。
+func (r *whitelistDenyRepository) Add(ctx context.Context, d *models.WhitelistDeny) (bool, error) {
+	const q = `
+		INSERT INTO whitelist_denylist (
+			discord_user_id,
+			vrc_user_id,
+			reason,
+			added_by
+		) VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING
+		RETURNING id, created_at;
+	`
+	err := conn(ctx, r.db).QueryRowContext(ctx, q,
+		nullableString(d.DiscordUserID),
+		nullableString(d.VRCUserID),
+		d.Reason,
+		d.AddedBy,
+	).Scan(&d.ID, &d.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Discord ID か VRChat ID のどちらかに一致する1件。空の引数では探さない。無ければ nil。
+func (r *whitelistDenyRepository) Find(ctx context.Context, discordID, vrcUserID string) (*models.WhitelistDeny, error) {
+	const q = `
+		SELECT ` + whitelistDenyColumns + `
+		FROM whitelist_denylist
+		WHERE ($1::text <> '' AND discord_user_id = $1::text)
+		   OR ($2::text <> '' AND vrc_user_id = $2::text)
+		ORDER BY id ASC
+		LIMIT 1;
+	`
+	d, err := scanWhitelistDeny(conn(ctx, r.db).QueryRowContext(ctx, q, discordID, vrcUserID))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return d, err
+}
+
+// 新しい順
+func (r *whitelistDenyRepository) List(ctx context.Context) ([]models.WhitelistDeny, error) {
+	const q = `
+		SELECT ` + whitelistDenyColumns + `
+		FROM whitelist_denylist
+		ORDER BY id DESC;
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]models.WhitelistDeny, 0)
+	for rows.Next() {
+		d, err := scanWhitelistDeny(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// 削除して、消した1件を返す。無ければ nil。
+func (r *whitelistDenyRepository) Delete(ctx context.Context, id uint64) (*models.WhitelistDeny, error) {
+	const q = `
+		DELETE FROM whitelist_denylist
+		WHERE id = $1
+		RETURNING ` + whitelistDenyColumns + `;
+	`
+	d, err := scanWhitelistDeny(conn(ctx, r.db).QueryRowContext(ctx, q, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return d, err
+}
+
+func scanWhitelistDeny(row rowScanner) (*models.WhitelistDeny, error) {
+	var (
+		d                  models.WhitelistDeny
+		discordID, vrcUser sql.NullString
+	)
+	if err := row.Scan(
+		&d.ID,
+		&discordID,
+		&vrcUser,
+		&d.Reason,
+		&d.AddedBy,
+		&d.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+	d.DiscordUserID = discordID.String
+	d.VRCUserID = vrcUser.String
+	return &d, nil
+}
+
+// 空文字は NULL として保存する
+func nullableString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"backend/internal/models"
+	"context"
+	"database/sql"
+)
+
+type WhitelistListRepository interface {
+	Create(ctx context.Context, l *models.WhitelistList) error
+	GetByName(ctx context.Context, name string) (*models.WhitelistList, error)
+	List(ctx context.Context) ([]models.WhitelistList, error)
+	ListByWhitelistUserID(ctx context.Context, whitelistUserID uint64) ([]models.WhitelistList, error)
+	AddMember(ctx context.Context, listID, whitelistUserID uint64) (bool, error)
+	RemoveMember(ctx context.Context, listID, whitelistUserID uint64) (bool, error)
+	ListMembers(ctx context.Context, listID uint64) ([]models.WhitelistUser, error)
+	SetRegistrationWindow(ctx context.Context, listID uint64, w models.RegistrationWindow) error
+}
+
+type whitelistListRepository struct {
+	db *sql.DB
+}
+
+func NewWhitelistListRepository(db *sql.DB) WhitelistListRepository {
+	return &whitelistListRepository{db: db}
+}
+
+// 作成。ID と CreatedAt は DB 側で振ったものを l に書き戻す。
+// 同名のリストがあれば ErrDuplicate。
+func (r *whitelistListRepository) Create(ctx context.Context, l *models.WhitelistList) error {
+	const q = `
+		INSERT INTO whitelist_lists (
+			name,
+			description,
+			self_service
+		) VALUES ($1, $2, $3)
+		RETURNING id, created_at;
+	`
+	err := conn(ctx, r.db).QueryRowContext(ctx, q,
+		l.Name,
+		l.Description,
+		l.SelfService,
+	).Scan(&l.ID, &l.CreatedAt)
+	if isUniqueViolation(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (r *whitelistListRepository) GetByName(ctx context.Context, name string) (*models.WhitelistList, error) {
+	const q = `
+		SELECT id, name, description, self_service, registration_opens_at, registration_closes_at, created_at
+		FROM whitelist_lists
+		WHERE name = $1
+		LIMIT 1;
+	`
+	var l models.WhitelistList
+	if err := conn(ctx, r.db).QueryRowContext(ctx, q, name).Scan(
+		&l.ID,
+		&l.Name,
+		&l.Description,
+		&l.SelfService,
+		&l.RegistrationWindow.OpensAt,
+		&l.RegistrationWindow.ClosesAt,
+		&l.CreatedAt,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (r *whitelistListRepository) List(ctx context.Context) ([]models.WhitelistList, error) {
+	const q = `
+		SELECT id, name, description, self_service, registration_opens_at, registration_closes_at, created_at
+		FROM whitelist_lists
+		ORDER BY name ASC;
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	return scanWhitelistLists(rows)
+}
+
+// 指定した紐付けが所属しているリスト
+func (r *whitelistListRepository) ListByWhitelistUserID(ctx context.Context, whitelistUserID uint64) ([]models.WhitelistList, error) {
+	const q = `
+		SELECT l.id, l.name, l.description, l.self_service, l.registration_opens_at, l.registration_closes_at, l.created_at
+		FROM whitelist_lists l
+		JOIN whitelist_list_members m ON m.list_id = l.id
+		WHERE m.whitelist_user_id = $1
+		ORDER BY l.name ASC;
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, q, whitelistUserID)
+	if err != nil {
+		return nil, err
+	}
+	return scanWhitelistLists(rows)
+}
+
+// 追加。既に所属していれば false。
+func (r *whitelistListRepository) AddMember(ctx context.Context, listID, whitelistUserID uint64) (bool, error) {
+	const q = `
+		INSERT INTO whitelist_list_members (list_id, whitelist_user_id)
+		VALUES ($1, $2)
+		ON CONFLICT (list_id, whitelist_user_id) DO NOTHING;
+	`
+	return r.execMembership(ctx, q, listID, whitelistUserID)
+}
+
+// 削除。所属していなければ false。
+func (r *whitelistListRepository) RemoveMember(ctx context.Context, listID, whitelistUserID uint64) (bool, error) {
+	const q = `DELETE FROM whitelist_list_members WHERE list_id = $1 AND whitelist_user_id = $2`
+	return r.execMembership(ctx, q, listID, whitelistUserID)
+}
+
+// 所属の追加・削除を実行し、変わったときだけバージョンを進める
+func (r *whitelistListRepository) execMembership(ctx context.Context, q string, listID, whitelistUserID uint64) (bool, error) {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, q, listID, whitelistUserID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if n == 0 {
+		return false, nil
+	}
+	if err := bumpVersion(ctx, tx); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// リストの所属者。エクスポート用なので whitelist_users.id 昇順（登録順）。
+// 論理削除された紐付けは所属が残っていても返さない（復元すれば戻る）。本人確認待ちも返さない。
+func (r *whitelistListRepository) ListMembers(ctx context.Context, listID uint64) ([]models.WhitelistUser, error) {
+	const q = `
+		SELECT ` + whitelistUserColumns + `
+		FROM whitelist_users
+		JOIN whitelist_list_members m ON m.whitelist_user_id = whitelist_users.id
+		WHERE m.list_id = $1 AND whitelist_users.deleted_at IS NULL AND whitelist_users.verified_at IS NOT NULL
+		ORDER BY whitelist_users.id ASC;
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, q, listID)
+	if err != nil {
+		return nil, err
+	}
+	return scanWhitelistUsers(rows)
+}
+
+// リストに参加できる期間を置き換える（所属は変わらないのでバージョンは進めない）
+func (r *whitelistListRepository) SetRegistrationWindow(ctx context.Context, listID uint64, w models.RegistrationWindow) error {
+	const q = `
+		UPDATE whitelist_lists
+		SET registration_opens_at = $2, registration_closes_at = $3
+		WHERE id = $1;
+	`
+	_, err := conn(ctx, r.db).ExecContext(ctx, q, listID, w.OpensAt, w.ClosesAt)
+	return err
+}
+
+func scanWhitelistLists(rows *sql.Rows) ([]models.WhitelistList, error) {
+	defer rows.Close()
+
+	lists := make([]models.WhitelistList, 0)
+	for rows.Next() {
+		var l models.WhitelistList
+		if err := rows.Scan(
+			&l.ID,
+			&l.Name,
+			&l.Description,
+			&l.SelfService,
+			&l.RegistrationWindow.OpensAt,
+			&l.RegistrationWindow.ClosesAt,
+			&l.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		lists = append(lists, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return lists, nil
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"backend/internal/models"
+	"context"
+	"database/sql"
+	"time"
+)
+
+type RegisterLimitRepository interface {
+	Get(ctx context.Context, discordID string) (*models.RegisterLimit, error)
+	ReserveAttempt(ctx context.Context, discordID string, at, notAfter time.Time) (bool, error)
+	ReleaseAttempt(ctx context.Context, discordID string, at, resetTo time.Time) error
+	RecordChange(ctx context.Context, discordID string, at, resetBefore time.Time) error
+}
+
+type registerLimitRepository struct {
+	db *sql.DB
+}
+
+func NewRegisterLimitRepository(db *sql.DB) RegisterLimitRepository {
+	return &registerLimitRepository{db: db}
+}
+
+// 無ければ nil（まだ一度も登録していない）
+func (r *registerLimitRepository) Get(ctx context.Context, discordID string) (*models.RegisterLimit, error) {
+	const q = `
+		SELECT discord_user_id, last_attempt_at, changes_since, change_count
+		FROM whitelist_register_limits
+		WHERE discord_user_id = $1;
+	`
+	var l models.RegisterLimit
+	if err := conn(ctx, r.db).QueryRowContext(ctx, q, discordID).Scan(
+		&l.DiscordUserID,
+		&l.LastAttemptAt,
+		&l.ChangesSince,
+		&l.ChangeCount,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &l, nil
+}
+
+// 登録を試みる枠を取る。前回が notAfter 以前（または初めて）なら at を残して true、まだ間隔が空いていなければ false。
+// 同時に来ても1件しか取れないよう、確認と書き込みを1つの文でやる。
+func (r *registerLimitRepository) ReserveAttempt(ctx context.Context, discordID string, at, notAfter time.Time) (bool, error) {
+	const q = `
+		INSERT INTO whitelist_register_limits (discord_user_id, last_attempt_at, changes_since, change_count)
+		VALUES ($1, $2, $2, 0)
+		ON CONFLICT (discord_user_id) DO UPDATE
+		SET last_attempt_at = EXCLUDED.last_attempt_at
+		WHERE whitelist_register_limits.last_attempt_at <= $3
+		RETURNING discord_user_id;
+	`
+	var id string
+	err := conn(ctx, r.db).QueryRowContext(ctx, q, discordID, at, notAfter).Scan(&id)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ReserveAttempt で取った枠を返す。at のまま残っていれば resetTo に戻す（その後に取り直されていれば何もしない）。
+func (r *registerLimitRepository) ReleaseAttempt(ctx context.Context, discordID string, at, resetTo time.Time) error {
+	const q = `
+		UPDATE whitelist_register_limits
+		SET last_attempt_at = $3
+		WHERE discord_user_id = $1 AND last_attempt_at = $2;
+	`
+	_, err := conn(ctx, r.db).ExecContext(ctx, q, discordID, at, resetTo)
+	return err
+}
+
+// VRChat アカウントを変えた回数を +1 する。数え始めが resetBefore 以前なら at から数え直す。
+func (r *registerLimitRepository) RecordChange(ctx context.Context, discordID string, at, resetBefore time.Time) error {
+	const q = `
+		INSERT INTO whitelist_register_limits (discord_user_id, last_attempt_at, changes_since, change_count)
+		VALUES ($1, $2, $2, 1)
+		ON CONFLICT (discord_user_id) DO UPDATE
+		SET
+			change_count  = CASE WHEN whitelist_register_limits.changes_since <= $3
+				THEN 1 ELSE whitelist_register_limits.change_count + 1 END,
+			changes_since = CASE WHEN whitelist_register_limits.changes_since <= $3
+				THEN EXCLUDED.changes_since ELSE whitelist_register_limits.changes_since END;
+	`
+	_, err := conn(ctx, r.db).ExecContext(ctx, q, discordID, at, resetBefore)
+	return err
+}
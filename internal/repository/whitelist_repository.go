@@ -1,156 +1,707 @@
-package repository
-
-import (
-	"backend/internal/models"
-	"context"
-	"database/sql"
-)
-
-type WhitelistRepository interface {
-	Upsert(ctx context.Context, u *models.WhitelistUser) error
-	GetByDiscordID(ctx context.Context, discordID string) (*models.WhitelistUser, error)
-	GetByVRCUserID(ctx context.Context, vrcUserID string) (*models.WhitelistUser, error)
-	ExistsByDiscordID(ctx context.Context, discordID string) (bool, error)
-	ExistsByVRCUserID(ctx context.Context, vrcUserID string) (bool, error)
-	RemoveByDiscordID(ctx context.Context, discordID string) error
-}
-
-type whitelistRepository struct {
-	db *sql.DB
-}
-
-func NewWhitelistRepository(db *sql.DB) WhitelistRepository {
-	return &whitelistRepository{db: db}
-}
-
-func (r *whitelistRepository) Upsert(ctx context.Context, u *models.WhitelistUser) error {
-	// discord_user_id / vrc_user_id の UNIQUE を利用してUpsert
-	const q = `
-		INSERT INTO whitelist_users (
-			discord_user_id,
-			vrc_user_id,
-			vrc_display_name,
-			vrc_avatar_url,
-			note
-		) VALUES ($1, $2, $3, $4, $5)
-		ON CONFLICT (discord_user_id) DO UPDATE
-		SET
-			vrc_user_id      = EXCLUDED.vrc_user_id,
-			vrc_display_name = EXCLUDED.vrc_display_name,
-			vrc_avatar_url   = EXCLUDED.vrc_avatar_url,
-			note             = EXCLUDED.note,
-			updated_at       = CURRENT_TIMESTAMP;
-	`
-	_, err := r.db.ExecContext(ctx, q,
-		u.DiscordUserID,
-		u.VRCUserID,
-		u.VRCDisplayName,
-		u.VRCAvatarURL,
-		u.Note,
-	)
-	return err
-}
-
-func (r *whitelistRepository) GetByDiscordID(ctx context.Context, discordID string) (*models.WhitelistUser, error) {
-	const q = `
-		SELECT
-			id,
-			discord_user_id,
-			vrc_user_id,
-			vrc_display_name,
-			COALESCE(vrc_avatar_url, ''),
-			note,
-			created_at,
-			updated_at
-		FROM whitelist_users
-		WHERE discord_user_id = $1
-		LIMIT 1;
-	`
-	row := r.db.QueryRowContext(ctx, q, discordID)
-
-	var u models.WhitelistUser
-	if err := row.Scan(
-		&u.ID,
-		&u.DiscordUserID,
-		&u.VRCUserID,
-		&u.VRCDisplayName,
-		&u.VRCAvatarURL,
-		&u.Note,
-		&u.CreatedAt,
-		&u.UpdatedAt,
-	); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
-		return nil, err
-	}
-	return &u, nil
-}
-
-func (r *whitelistRepository) GetByVRCUserID(ctx context.Context, vrcUserID string) (*models.WhitelistUser, error) {
-	const q = `
-		SELECT
-			id,
-			discord_user_id,
-			vrc_user_id,
-			vrc_display_name,
-			COALESCE(vrc_avatar_url, ''),
-			note,
-			created_at,
-			updated_at
-		FROM whitelist_users
-		WHERE vrc_user_id = $1
-		LIMIT 1;
-	`
-	row := r.db.QueryRowContext(ctx, q, vrcUserID)
-
-	var u models.WhitelistUser
-	if err := row.Scan(
-		&u.ID,
-		&u.DiscordUserID,
-		&u.VRCUserID,
-		&u.VRCDisplayName,
-		&u.VRCAvatarURL,
-		&u.Note,
-		&u.CreatedAt,
-		&u.UpdatedAt,
-	); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
-		return nil, err
-	}
-	return &u, nil
-}
-
-func (r *whitelistRepository) ExistsByDiscordID(ctx context.Context, discordID string) (bool, error) {
-	const q = `SELECT 1 FROM whitelist_users WHERE discord_user_id = $1 LIMIT 1`
-	var x int
-	err := r.db.QueryRowContext(ctx, q, discordID).Scan(&x)
-	if err == sql.ErrNoRows {
-		return false, nil
-	}
-	if err != nil {
-		return false, err
-	}
-	return true, nil
-}
-
-func (r *whitelistRepository) ExistsByVRCUserID(ctx context.Context, vrcUserID string) (bool, error) {
-	const q = `SELECT 1 FROM whitelist_users WHERE vrc_user_id = $1 LIMIT 1`
-	var x int
-	err := r.db.QueryRowContext(ctx, q, vrcUserID).Scan(&x)
-	if err == sql.ErrNoRows {
-		return false, nil
-	}
-	if err != nil {
-		return false, err
-	}
-	return true, nil
-}
-
-func (r *whitelistRepository) RemoveByDiscordID(ctx context.Context, discordID string) error {
-	const q = `DELETE FROM whitelist_users WHERE discord_user_id = $1`
-	_, err := r.db.ExecContext(ctx, q, discordID)
-	return err
-}
+package repository
+
+import (
+	"backend/internal/models"
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+type WhitelistRepository interface {
+	Upsert(ctx context.Context, u *models.WhitelistUser) error
+	GetByDiscordID(ctx context.Context, discordID string) (*models.WhitelistUser, error)
+	GetByVRCUserID(ctx context.Context, vrcUserID string) (*models.WhitelistUser, error)
+	ListByVRCUserID(ctx context.Context, vrcUserID string) ([]models.WhitelistUser, error)
+	ExistsByDiscordID(ctx context.Context, discordID string) (bool, error)
+	ExistsByVRCUserID(ctx context.Context, vrcUserID string) (bool, error)
+	CountActive(ctx context.Context) (int, error)
+	RemoveByDiscordID(ctx context.Context, discordID string) (*models.WhitelistUser, error)
+	List(ctx context.Context) ([]models.WhitelistUser, error)
+	Each(ctx context.Context, fn func(u *models.WhitelistUser) error) error
+	Search(ctx context.Context, f models.WhitelistUserFilter, after *models.WhitelistUserCursor) ([]models.WhitelistUser, error)
+	GetVersion(ctx context.Context) (*models.WhitelistVersion, error)
+	SetExpiresAt(ctx context.Context, discordID string, expiresAt *time.Time) (bool, error)
+	SetNote(ctx context.Context, discordID, note string) (bool, error)
+	RemoveExpired(ctx context.Context, now time.Time) ([]models.WhitelistUser, error)
+	Restore(ctx context.Context, discordID string) (*models.WhitelistUser, error)
+	MarkVerified(ctx context.Context, discordID string) (*models.WhitelistUser, error)
+	RemoveUnverified(ctx context.Context, now time.Time) ([]models.WhitelistUser, error)
+	RemovePendingByVRCUserID(ctx context.Context, vrcUserID, exceptDiscordID string) ([]models.WhitelistUser, error)
+	UpdateVRCProfile(ctx context.Context, id uint64, vrcUserID, displayName, avatarURL string) (*models.WhitelistNameChange, error)
+	ListNameHistory(ctx context.Context, discordID string, limit int) ([]models.WhitelistNameChange, error)
+	PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error)
+}
+
+type whitelistRepository struct {
+	db *sql.DB
+}
+
+func NewWhitelistRepository(db *sql.DB) WhitelistRepository {
+	return &whitelistRepository{db: db}
+}
+
+// whitelist_users を models.WhitelistUser に読むときの SELECT 列。
+// 順番は scanWhitelistUser と揃えること。
+const whitelistUserColumns = `
+	whitelist_users.id,
+	whitelist_users.discord_user_id,
+	whitelist_users.vrc_user_id,
+	whitelist_users.vrc_display_name,
+	COALESCE(whitelist_users.vrc_avatar_url, ''),
+	whitelist_users.note,
+	whitelist_users.expires_at,
+	whitelist_users.verified_at,
+	whitelist_users.verify_code,
+	whitelist_users.verify_until,
+	whitelist_users.created_at,
+	whitelist_users.updated_at`
+
+// *sql.Row / *sql.Rows のどちらからでも読めるように
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanWhitelistUser(row rowScanner) (*models.WhitelistUser, error) {
+	var u models.WhitelistUser
+	if err := row.Scan(
+		&u.ID,
+		&u.DiscordUserID,
+		&u.VRCUserID,
+		&u.VRCDisplayName,
+		&u.VRCAvatarURL,
+		&u.Note,
+		&u.ExpiresAt,
+		&u.VerifiedAt,
+		&u.VerifyCode,
+		&u.VerifyUntil,
+		&u.CreatedAt,
+		&u.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
+
+// rows を最後まで読んで WhitelistUser の配列にする
+func scanWhitelistUsers(rows *sql.Rows) ([]models.WhitelistUser, error) {
+	defer rows.Close()
+
+	users := make([]models.WhitelistUser, 0)
+	for rows.Next() {
+		u, err := scanWhitelistUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// 論理削除された行があればそれを生き返らせる（discord_user_id は削除済みも含めて一意）。
+func (r *whitelistRepository) Upsert(ctx context.Context, u *models.WhitelistUser) error {
+	// 削除済みの行を生き返らせるときは新規登録と同じ扱いにしたいので、
+	// 以前のリスト所属は消しておく（元に戻したいときは Restore を使う）
+	const clearMembershipsQ = `
+		DELETE FROM whitelist_list_members
+		WHERE whitelist_user_id IN (
+			SELECT id FROM whitelist_users
+			WHERE discord_user_id = $1 AND deleted_at IS NOT NULL
+		);
+	`
+	// discord_user_id / vrc_user_id の UNIQUE を利用してUpsert
+	// 削除済みの行を生き返らせるときは期限もリセットする
+	// メモは管理者が SetNote で書くものなので、登録し直しでは上書きしない（u.Note は新規のときだけ使う）
+	const q = `
+		INSERT INTO whitelist_users (
+			discord_user_id,
+			vrc_user_id,
+			vrc_display_name,
+			vrc_avatar_url,
+			note,
+			verified_at,
+			verify_code,
+			verify_until
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (discord_user_id) DO UPDATE
+		SET
+			vrc_user_id      = EXCLUDED.vrc_user_id,
+			vrc_display_name = EXCLUDED.vrc_display_name,
+			vrc_avatar_url   = EXCLUDED.vrc_avatar_url,
+			verified_at      = EXCLUDED.verified_at,
+			verify_code      = EXCLUDED.verify_code,
+			verify_until     = EXCLUDED.verify_until,
+			expires_at       = CASE WHEN whitelist_users.deleted_at IS NULL THEN whitelist_users.expires_at END,
+			deleted_at       = NULL,
+			updated_at       = CURRENT_TIMESTAMP;
+	`
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, clearMembershipsQ, u.DiscordUserID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, q,
+		u.DiscordUserID,
+		u.VRCUserID,
+		u.VRCDisplayName,
+		u.VRCAvatarURL,
+		u.Note,
+		u.VerifiedAt,
+		u.VerifyCode,
+		u.VerifyUntil,
+	); err != nil {
+		if isUniqueViolation(err) {
+			return ErrDuplicate
+		}
+		return err
+	}
+	// 変更と同じトランザクションでバージョンを進める
+	if err := bumpVersion(ctx, tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *whitelistRepository) GetByDiscordID(ctx context.Context, discordID string) (*models.WhitelistUser, error) {
+	const q = `
+		SELECT ` + whitelistUserColumns + `
+		FROM whitelist_users
+		WHERE discord_user_id = $1 AND deleted_at IS NULL
+		LIMIT 1;
+	`
+	u, err := scanWhitelistUser(conn(ctx, r.db).QueryRowContext(ctx, q, discordID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return u, nil
+}
+
+// その VRChat アカウントを本人確認済みで持っている紐付け（確認済みは1人だけ）。無ければ nil。
+// 本人確認待ちの紐付けはアカウントを押さえないので返さない。
+func (r *whitelistRepository) GetByVRCUserID(ctx context.Context, vrcUserID string) (*models.WhitelistUser, error) {
+	const q = `
+		SELECT ` + whitelistUserColumns + `
+		FROM whitelist_users
+		WHERE vrc_user_id = $1 AND deleted_at IS NULL AND verified_at IS NOT NULL
+		LIMIT 1;
+	`
+	u, err := scanWhitelistUser(conn(ctx, r.db).QueryRowContext(ctx, q, vrcUserID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return u, nil
+}
+
+func (r *whitelistRepository) ExistsByDiscordID(ctx context.Context, discordID string) (bool, error) {
+	const q = `SELECT 1 FROM whitelist_users WHERE discord_user_id = $1 AND deleted_at IS NULL AND verified_at IS NOT NULL LIMIT 1`
+	var x int
+	err := conn(ctx, r.db).QueryRowContext(ctx, q, discordID).Scan(&x)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// 定員の判定用に、論理削除されていない紐付けを数える（本人確認待ちも枠を使う）
+func (r *whitelistRepository) CountActive(ctx context.Context) (int, error) {
+	const q = `SELECT COUNT(*) FROM whitelist_users WHERE deleted_at IS NULL`
+	var n int
+	if err := conn(ctx, r.db).QueryRowContext(ctx, q).Scan(&n); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (r *whitelistRepository) ExistsByVRCUserID(ctx context.Context, vrcUserID string) (bool, error) {
+	const q = `SELECT 1 FROM whitelist_users WHERE vrc_user_id = $1 AND deleted_at IS NULL AND verified_at IS NOT NULL LIMIT 1`
+	var x int
+	err := conn(ctx, r.db).QueryRowContext(ctx, q, vrcUserID).Scan(&x)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// 論理削除。行は PurgeDeleted まで残り、Restore で元に戻せる。
+// 外した紐付けを返す。無ければ（同時に外された場合も）nil。
+func (r *whitelistRepository) RemoveByDiscordID(ctx context.Context, discordID string) (*models.WhitelistUser, error) {
+	const q = `
+		UPDATE whitelist_users
+		SET
+			deleted_at = CURRENT_TIMESTAMP,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE discord_user_id = $1 AND deleted_at IS NULL
+		RETURNING ` + whitelistUserColumns + `;
+	`
+
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	u, err := scanWhitelistUser(tx.QueryRowContext(ctx, q, discordID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	// 実際に消えたときだけバージョンを進める
+	if err := bumpVersion(ctx, tx); err != nil {
+		return nil, err
+	}
+	return u, tx.Commit()
+}
+
+// 全件取得。エクスポート用なので id 昇順（登録順）で安定させる。
+// 本人確認が済んでいない紐付けは含めない。
+func (r *whitelistRepository) List(ctx context.Context) ([]models.WhitelistUser, error) {
+	const q = `
+		SELECT ` + whitelistUserColumns + `
+		FROM whitelist_users
+		WHERE deleted_at IS NULL AND verified_at IS NOT NULL
+		ORDER BY id ASC;
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	return scanWhitelistUsers(rows)
+}
+
+// 全件を id 昇順で1行ずつ fn に渡す。全件をメモリに載せたくない大きなエクスポート用。
+// fn がエラーを返したらそこで打ち切ってそのエラーを返す。
+func (r *whitelistRepository) Each(ctx context.Context, fn func(u *models.WhitelistUser) error) error {
+	const q = `
+		SELECT ` + whitelistUserColumns + `
+		FROM whitelist_users
+		WHERE deleted_at IS NULL
+		ORDER BY id ASC;
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		u, err := scanWhitelistUser(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(u); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// 管理画面向けの検索。f.Sort の時刻 → id の新しい順に f.Limit 件。
+// after があればその位置より後ろ（古い側）から取る。f.Sort と f.Limit は呼び出し側で正規化しておくこと。
+func (r *whitelistRepository) Search(ctx context.Context, f models.WhitelistUserFilter, after *models.WhitelistUserCursor) ([]models.WhitelistUser, error) {
+	// 列名はプレースホルダにできないので、決まった値からだけ選ぶ
+	sortColumn := "created_at"
+	if f.Sort == models.WhitelistSortUpdated {
+		sortColumn = "updated_at"
+	}
+
+	var (
+		afterAt time.Time
+		afterID uint64
+	)
+	if after != nil {
+		afterAt, afterID = after.At, after.ID
+	}
+
+	q := `
+		SELECT ` + whitelistUserColumns + `
+		FROM whitelist_users
+		WHERE deleted_at IS NULL
+		  AND ($1::text = '' OR discord_user_id = $1::text)
+		  AND ($2::text = '' OR vrc_user_id = $2::text)
+		  AND ($3::text = '' OR vrc_display_name ILIKE '%' || $3::text || '%')
+		  AND ($5::bigint = 0 OR (` + sortColumn + `, id) < ($4::timestamptz, $5::bigint))
+		ORDER BY ` + sortColumn + ` DESC, id DESC
+		LIMIT $6;
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, q,
+		f.DiscordUserID,
+		f.VRCUserID,
+		escapeLike(f.VRCDisplayName),
+		afterAt,
+		int64(afterID),
+		f.Limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanWhitelistUsers(rows)
+}
+
+// LIKE のワイルドカードを文字どおりに扱わせる（エスケープ文字はデフォルトの \）
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// 有効期限の設定。nil なら無期限に戻す。対象が無ければ false。
+// 期限は紐付けの中身ではないのでバージョンは進めない（期限切れで外れたときに進む）。
+func (r *whitelistRepository) SetExpiresAt(ctx context.Context, discordID string, expiresAt *time.Time) (bool, error) {
+	const q = `
+		UPDATE whitelist_users
+		SET
+			expires_at = $2,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE discord_user_id = $1 AND deleted_at IS NULL;
+	`
+	res, err := conn(ctx, r.db).ExecContext(ctx, q, discordID, expiresAt)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// 管理者用メモの書き換え。紐付けが無ければ false。
+// メモはワールド向けエクスポートに出ないのでバージョンは進めない。
+func (r *whitelistRepository) SetNote(ctx context.Context, discordID, note string) (bool, error) {
+	const q = `
+		UPDATE whitelist_users
+		SET
+			note       = $2,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE discord_user_id = $1 AND deleted_at IS NULL;
+	`
+	res, err := conn(ctx, r.db).ExecContext(ctx, q, discordID, note)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// 期限切れをまとめて論理削除し、外れた紐付けを返す
+func (r *whitelistRepository) RemoveExpired(ctx context.Context, now time.Time) ([]models.WhitelistUser, error) {
+	const q = `
+		UPDATE whitelist_users
+		SET
+			deleted_at = CURRENT_TIMESTAMP,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE deleted_at IS NULL AND expires_at IS NOT NULL AND expires_at <= $1
+		RETURNING ` + whitelistUserColumns + `;
+	`
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, q, now)
+	if err != nil {
+		return nil, err
+	}
+	removed, err := scanWhitelistUsers(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(removed) > 0 {
+		if err := bumpVersion(ctx, tx); err != nil {
+			return nil, err
+		}
+	}
+	return removed, tx.Commit()
+}
+
+// 論理削除を取り消して、復元した紐付けを返す。削除済みの行が無ければ nil。
+// その VRChat アカウントが既に別の人に使われていれば ErrDuplicate。
+// 削除中に期限が過ぎていた場合は無期限に戻す（そうしないと即座にまた外れる）。
+func (r *whitelistRepository) Restore(ctx context.Context, discordID string) (*models.WhitelistUser, error) {
+	const q = `
+		UPDATE whitelist_users
+		SET
+			deleted_at = NULL,
+			expires_at = CASE WHEN expires_at > CURRENT_TIMESTAMP THEN expires_at END,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE discord_user_id = $1 AND deleted_at IS NOT NULL
+		RETURNING ` + whitelistUserColumns + `;
+	`
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	u, err := scanWhitelistUser(tx.QueryRowContext(ctx, q, discordID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		if isUniqueViolation(err) {
+			return nil, ErrDuplicate
+		}
+		return nil, err
+	}
+	if err := bumpVersion(ctx, tx); err != nil {
+		return nil, err
+	}
+	return u, tx.Commit()
+}
+
+// 本人確認済みにして、更新後の紐付けを返す。未確認の紐付けが無ければ nil。
+// 同じ VRChat アカウントを他の人が先に確認済みにしていれば ErrDuplicate。
+// エクスポートに出るようになるのでバージョンを進める。
+func (r *whitelistRepository) MarkVerified(ctx context.Context, discordID string) (*models.WhitelistUser, error) {
+	const q = `
+		UPDATE whitelist_users
+		SET
+			verified_at  = CURRENT_TIMESTAMP,
+			verify_code  = '',
+			verify_until = NULL,
+			updated_at   = CURRENT_TIMESTAMP
+		WHERE discord_user_id = $1 AND deleted_at IS NULL AND verified_at IS NULL
+		RETURNING ` + whitelistUserColumns + `;
+	`
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	u, err := scanWhitelistUser(tx.QueryRowContext(ctx, q, discordID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		if isUniqueViolation(err) {
+			return nil, ErrDuplicate
+		}
+		return nil, err
+	}
+	if err := bumpVersion(ctx, tx); err != nil {
+		return nil, err
+	}
+	return u, tx.Commit()
+}
+
+// 本人確認の期限が過ぎた未確認の紐付けをまとめて論理削除し、外れた紐付けを返す。
+// 未確認の紐付けはエクスポートに出ていないのでバージョンは進めない。
+func (r *whitelistRepository) RemoveUnverified(ctx context.Context, now time.Time) ([]models.WhitelistUser, error) {
+	const q = `
+		UPDATE whitelist_users
+		SET
+			deleted_at = CURRENT_TIMESTAMP,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE deleted_at IS NULL AND verified_at IS NULL AND verify_until IS NOT NULL AND verify_until <= $1
+		RETURNING ` + whitelistUserColumns + `;
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, q, now)
+	if err != nil {
+		return nil, err
+	}
+	return scanWhitelistUsers(rows)
+}
+
+// その VRChat アカウントを使っている紐付けを、本人確認待ちも含めてすべて返す（登録順）
+func (r *whitelistRepository) ListByVRCUserID(ctx context.Context, vrcUserID string) ([]models.WhitelistUser, error) {
+	const q = `
+		SELECT ` + whitelistUserColumns + `
+		FROM whitelist_users
+		WHERE vrc_user_id = $1 AND deleted_at IS NULL
+		ORDER BY id ASC;
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, q, vrcUserID)
+	if err != nil {
+		return nil, err
+	}
+	return scanWhitelistUsers(rows)
+}
+
+// 同じ VRChat アカウントで本人確認待ちになっている、exceptDiscordID 以外の紐付けを論理削除して返す。
+// 誰かが本人確認を済ませたら、そのアカウントを待っていた他の人は外す。
+// 未確認の紐付けはエクスポートに出ていないのでバージョンは進めない。
+func (r *whitelistRepository) RemovePendingByVRCUserID(ctx context.Context, vrcUserID, exceptDiscordID string) ([]models.WhitelistUser, error) {
+	const q = `
+		UPDATE whitelist_users
+		SET
+			deleted_at = CURRENT_TIMESTAMP,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE vrc_user_id = $1 AND discord_user_id <> $2 AND deleted_at IS NULL AND verified_at IS NULL
+		RETURNING ` + whitelistUserColumns + `;
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, q, vrcUserID, exceptDiscordID)
+	if err != nil {
+		return nil, err
+	}
+	return scanWhitelistUsers(rows)
+}
+
+// VRChat 側の最新の表示名・アバターで上書きする。
+// 表示名が変わっていたら履歴を追記してバージョンを進め、その変更を返す（アバターだけなら nil）。
+// 対象が削除済み・存在しない・別の VRChat アカウントに付け替えられていれば何もせず nil。
+// （再同期は開始時の一覧を元に進むので、その間に付け替えられた行を古いアカウントの情報で上書きしないように）
+func (r *whitelistRepository) UpdateVRCProfile(ctx context.Context, id uint64, vrcUserID, displayName, avatarURL string) (*models.WhitelistNameChange, error) {
+	const selectQ = `
+		SELECT discord_user_id, vrc_user_id, vrc_display_name
+		FROM whitelist_users
+		WHERE id = $1 AND vrc_user_id = $2 AND deleted_at IS NULL
+		FOR UPDATE;
+	`
+	const updateQ = `
+		UPDATE whitelist_users
+		SET
+			vrc_display_name = $3,
+			vrc_avatar_url   = $4,
+			updated_at       = CURRENT_TIMESTAMP
+		WHERE id = $1 AND vrc_user_id = $2;
+	`
+	const historyQ = `
+		INSERT INTO whitelist_name_history (
+			discord_user_id,
+			vrc_user_id,
+			old_display_name,
+			new_display_name
+		) VALUES ($1, $2, $3, $4)
+		RETURNING id, changed_at;
+	`
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	c := models.WhitelistNameChange{NewDisplayName: displayName}
+	if err := tx.QueryRowContext(ctx, selectQ, id, vrcUserID).Scan(&c.DiscordUserID, &c.VRCUserID, &c.OldDisplayName); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, updateQ, id, vrcUserID, displayName, avatarURL); err != nil {
+		return nil, err
+	}
+
+	// アバターだけの変更はエクスポートの中身が変わらないので履歴もバージョンもそのまま
+	if c.OldDisplayName == displayName {
+		return nil, tx.Commit()
+	}
+	if err := tx.QueryRowContext(ctx, historyQ,
+		c.DiscordUserID,
+		c.VRCUserID,
+		c.OldDisplayName,
+		c.NewDisplayName,
+	).Scan(&c.ID, &c.ChangedAt); err != nil {
+		return nil, err
+	}
+	if err := bumpVersion(ctx, tx); err != nil {
+		return nil, err
+	}
+	return &c, tx.Commit()
+}
+
+// 表示名の変更履歴を新しい順に limit 件
+func (r *whitelistRepository) ListNameHistory(ctx context.Context, discordID string, limit int) ([]models.WhitelistNameChange, error) {
+	const q = `
+		SELECT
+			id,
+			discord_user_id,
+			vrc_user_id,
+			old_display_name,
+			new_display_name,
+			changed_at
+		FROM whitelist_name_history
+		WHERE discord_user_id = $1
+		ORDER BY id DESC
+		LIMIT $2;
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, q, discordID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changes := make([]models.WhitelistNameChange, 0)
+	for rows.Next() {
+		var c models.WhitelistNameChange
+		if err := rows.Scan(
+			&c.ID,
+			&c.DiscordUserID,
+			&c.VRCUserID,
+			&c.OldDisplayName,
+			&c.NewDisplayName,
+			&c.ChangedAt,
+		); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// deletedBefore より前に論理削除された行を物理削除する。リスト所属も CASCADE で消える。
+// 既に見えない行なのでバージョンは進めない。
+func (r *whitelistRepository) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	const q = `DELETE FROM whitelist_users WHERE deleted_at IS NOT NULL AND deleted_at < $1`
+	res, err := conn(ctx, r.db).ExecContext(ctx, q, deletedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// 現在のバージョン。まだ一度も変更がなければ Version=0, UpdatedAt はゼロ値。
+func (r *whitelistRepository) GetVersion(ctx context.Context) (*models.WhitelistVersion, error) {
+	const q = `SELECT version, updated_at FROM whitelist_version WHERE id = 1`
+
+	var v models.WhitelistVersion
+	err := conn(ctx, r.db).QueryRowContext(ctx, q).Scan(&v.Version, &v.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return &models.WhitelistVersion{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// whitelist_version を +1 する。行が無ければ作る。
+func bumpVersion(ctx context.Context, tx dbtx) error {
+	const q = `
+		INSERT INTO whitelist_version (id, version, updated_at)
+		VALUES (1, 1, CURRENT_TIMESTAMP)
+		ON CONFLICT (id) DO UPDATE
+		SET
+			version    = whitelist_version.version + 1,
+			updated_at = CURRENT_TIMESTAMP;
+	`
+	_, err := tx.ExecContext(ctx, q)
+	return err
+}
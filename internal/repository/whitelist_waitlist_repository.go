@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"backend/internal/models"
+	"context"
+	"database/sql"
+)
+
+type WhitelistWaitlistRepository interface {
+	Upsert(ctx context.Context, e *models.WhitelistWaitlistEntry) error
+	GetByDiscordID(ctx context.Context, discordID string) (*models.WhitelistWaitlistEntry, error)
+	Next(ctx context.Context) (*models.WhitelistWaitlistEntry, error)
+	List(ctx context.Context) ([]models.WhitelistWaitlistEntry, error)
+	Count(ctx context.Context) (int, error)
+	Delete(ctx context.Context, discordID string) (*models.WhitelistWaitlistEntry, error)
+}
+
+type whitelistWaitlistRepository struct {
+	db *sql.DB
+}
+
+func NewWhitelistWaitlistRepository(db *sql.DB) WhitelistWaitlistRepository {
+	return &whitelistWaitlistRepository{db: db}
+}
+
+// 順番（Position）は id の小さい順に数える。順番は scanWaitlistEntry と揃えること。
+const waitlistEntryColumns = `
+	w.id,
+	w.discord_user_id,
+	w.vrc_user_id,
+	w.vrc_display_name,
+	COALESCE(w.vrc_avatar_url, ''),
+	(SELECT COUNT(*) FROM whitelist_waitlist ahead WHERE ahead.id <= w.id),
+	w.created_at,
+	w.updated_at`
+
+// 並ぶ。既に並んでいれば VRChat アカウントだけ差し替えて順番はそのまま。
+func (r *whitelistWaitlistRepository) Upsert(ctx context.Context, e *models.WhitelistWaitlistEntry) error {
+	const q = `
+		INSERT INTO whitelist_waitlist (
+			discord_user_id,
+			vrc_user_id,
+			vrc_display_name,
+			vrc_avatar_url
+		) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (discord_user_id) DO UPDATE
+		SET
+			vrc_user_id      = EXCLUDED.vrc_user_id,
+			vrc_display_name = EXCLUDED.vrc_display_name,
+			vrc_avatar_url   = EXCLUDED.vrc_avatar_url,
+			updated_at       = CURRENT_TIMESTAMP
+		RETURNING id, created_at, updated_at;
+	`
+	return conn(ctx, r.db).QueryRowContext(ctx, q,
+		e.DiscordUserID,
+		e.VRCUserID,
+		e.VRCDisplayName,
+		nullableString(e.VRCAvatarURL),
+	).Scan(&e.ID, &e.CreatedAt, &e.UpdatedAt)
+}
+
+// 無ければ nil
+func (r *whitelistWaitlistRepository) GetByDiscordID(ctx context.Context, discordID string) (*models.WhitelistWaitlistEntry, error) {
+	const q = `SELECT ` + waitlistEntryColumns + ` FROM whitelist_waitlist w WHERE w.discord_user_id = $1`
+	return scanWaitlistEntry(conn(ctx, r.db).QueryRowContext(ctx, q, discordID))
+}
+
+// 先頭（次に繰り上がる人）。誰も並んでいなければ nil。
+func (r *whitelistWaitlistRepository) Next(ctx context.Context) (*models.WhitelistWaitlistEntry, error) {
+	const q = `SELECT ` + waitlistEntryColumns + ` FROM whitelist_waitlist w ORDER BY w.id ASC LIMIT 1`
+	return scanWaitlistEntry(conn(ctx, r.db).QueryRowContext(ctx, q))
+}
+
+// 並んでいる順
+func (r *whitelistWaitlistRepository) List(ctx context.Context) ([]models.WhitelistWaitlistEntry, error) {
+	const q = `SELECT ` + waitlistEntryColumns + ` FROM whitelist_waitlist w ORDER BY w.id ASC`
+
+	rows, err := conn(ctx, r.db).QueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]models.WhitelistWaitlistEntry, 0)
+	for rows.Next() {
+		e, err := scanWaitlistEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (r *whitelistWaitlistRepository) Count(ctx context.Context) (int, error) {
+	const q = `SELECT COUNT(*) FROM whitelist_waitlist`
+	var n int
+	if err := conn(ctx, r.db).QueryRowContext(ctx, q).Scan(&n); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// 列から外し、外した1件を返す（順番は外す前のもの）。並んでいなければ nil。
+func (r *whitelistWaitlistRepository) Delete(ctx context.Context, discordID string) (*models.WhitelistWaitlistEntry, error) {
+	tx, err := beginTx(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	const sel = `SELECT ` + waitlistEntryColumns + ` FROM whitelist_waitlist w WHERE w.discord_user_id = $1 FOR UPDATE`
+	e, err := scanWaitlistEntry(tx.QueryRowContext(ctx, sel, discordID))
+	if err != nil || e == nil {
+		return nil, err
+	}
+
+	const del = `DELETE FROM whitelist_waitlist WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, del, e.ID); err != nil {
+		return nil, err
+	}
+	return e, tx.Commit()
+}
+
+// 1行読む。*sql.Row で行が無ければ nil, nil。
+func scanWaitlistEntry(row rowScanner) (*models.WhitelistWaitlistEntry, error) {
+	var e models.WhitelistWaitlistEntry
+	if err := row.Scan(
+		&e.ID,
+		&e.DiscordUserID,
+		&e.VRCUserID,
+		&e.VRCDisplayName,
+		&e.VRCAvatarURL,
+		&e.Position,
+		&e.CreatedAt,
+		&e.UpdatedAt,
+	); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &e, nil
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"backend/internal/models"
+	"context"
+	"database/sql"
+)
+
+type RegistrationWindowRepository interface {
+	Get(ctx context.Context) (*models.RegistrationWindow, error)
+	Set(ctx context.Context, w models.RegistrationWindow) error
+	HasOverride(ctx context.Context, discordID string) (bool, error)
+	AddOverride(ctx context.Context, o *models.RegistrationOverride) (bool, error)
+	RemoveOverride(ctx context.Context, discordID string) (bool, error)
+	ListOverrides(ctx context.Context) ([]models.RegistrationOverride, error)
+}
+
+type registrationWindowRepository struct {
+	db *sql.DB
+}
+
+func NewRegistrationWindowRepository(db *sql.DB) RegistrationWindowRepository {
+	return &registrationWindowRepository{db: db}
+}
+
+// 本人による登録の受付期間。まだ一度も設定していなければ制限なし（両方 nil）。
+func (r *registrationWindowRepository) Get(ctx context.Context) (*models.RegistrationWindow, error) {
+	const q = `SELECT opens_at, closes_at FROM whitelist_registration_window WHERE id = 1`
+
+	var w models.RegistrationWindow
+	err := conn(ctx, r.db).QueryRowContext(ctx, q).Scan(&w.OpensAt, &w.ClosesAt)
+	if err == sql.ErrNoRows {
+		return &models.RegistrationWindow{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &w, nil
+}
+
+// 受付期間を置き換える。行が無ければ作る。
+func (r *registrationWindowRepository) Set(ctx context.Context, w models.RegistrationWindow) error {
+	const q = `
+		INSERT INTO whitelist_registration_window (id, opens_at, closes_at, updated_at)
+		VALUES (1, $1, $2, CURRENT_TIMESTAMP)
+		ON CONFLICT (id) DO UPDATE
+		SET
+			opens_at   = EXCLUDED.opens_at,
+			closes_at  = EXCLUDED.closes_at,
+			updated_at = CURRENT_TIMESTAMP;
+	`
+	_, err := conn(ctx, r.db).ExecContext(ctx, q, w.OpensAt, w.ClosesAt)
+	return err
+}
+
+func (r *registrationWindowRepository) HasOverride(ctx context.Context, discordID string) (bool, error) {
+	const q = `SELECT EXISTS (SELECT 1 FROM whitelist_registration_overrides WHERE discord_user_id = $1)`
+
+	var ok bool
+	if err := conn(ctx, r.db).QueryRowContext(ctx, q, discordID).Scan(&ok); err != nil {
+		return false, err
+	}
+	return ok, nil
+}
+
+// 追加。既に許可されていれば false（granted_by も CreatedAt も最初のまま）。
+func (r *registrationWindowRepository) AddOverride(ctx context.Context, o *models.RegistrationOverride) (bool, error) {
+	const q = `
+		INSERT INTO whitelist_registration_overrides (discord_user_id, granted_by)
+		VALUES ($1, $2)
+		ON CONFLICT (discord_user_id) DO NOTHING
+		RETURNING created_at;
+	`
+	err := conn(ctx, r.db).QueryRowContext(ctx, q, o.DiscordUserID, o.GrantedBy).Scan(&o.CreatedAt)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// 削除。許可されていなければ false。
+func (r *registrationWindowRepository) RemoveOverride(ctx context.Context, discordID string) (bool, error) {
+	const q = `DELETE FROM whitelist_registration_overrides WHERE discord_user_id = $1`
+
+	res, err := conn(ctx, r.db).ExecContext(ctx, q, discordID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// 許可した順
+func (r *registrationWindowRepository) ListOverrides(ctx context.Context) ([]models.RegistrationOverride, error) {
+	const q = `
+		SELECT discord_user_id, granted_by, created_at
+		FROM whitelist_registration_overrides
+		ORDER BY created_at ASC, discord_user_id ASC;
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overrides := make([]models.RegistrationOverride, 0)
+	for rows.Next() {
+		var o models.RegistrationOverride
+		if err := rows.Scan(&o.DiscordUserID, &o.GrantedBy, &o.CreatedAt); err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return overrides, nil
+}
@@ -0,0 +1,34 @@
+package service
+
+import "context"
+
+// 監査ログに残す「どこから・誰が」の source 値
+const (
+	SourceDiscordButton  = "discord_button"
+	SourceDiscordModal   = "discord_modal"
+	SourceDiscordCommand = "discord_command"
+	SourceHTTPAPI        = "http_api"
+	SourceSystem         = "system"
+)
+
+// 変更を行った主体。ハンドラ側で context に載せて service に渡す。
+type Actor struct {
+	ID        string // Discord ユーザーID / API の呼び出し元など
+	Source    string // Source* のどれか
+	RequestID string // Echo の X-Request-Id / Discord の interaction ID
+	Admin     bool   // サーバー管理者として操作している（本人による登録の回数制限を受けない）
+}
+
+type actorKey struct{}
+
+func WithActor(ctx context.Context, a Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, a)
+}
+
+// context に載っていなければバックグラウンド処理（system）とみなす
+func ActorFrom(ctx context.Context) Actor {
+	if a, ok := ctx.Value(actorKey{}).(Actor); ok {
+		return a
+	}
+	return Actor{ID: "system", Source: SourceSystem}
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrInvalidToken = errors.New("invalid or revoked export token")
+)
+
+// 発行するトークンの接頭辞。ログや一覧で見分けやすくするため。
+const exportTokenPrefix = "wt_"
+
+type ExportTokenService interface {
+	Issue(ctx context.Context, worldName string) (*models.IssuedExportToken, error)
+	List(ctx context.Context) ([]models.ExportToken, error)
+	Revoke(ctx context.Context, id uint64) error
+	Authenticate(ctx context.Context, token string) (*models.ExportToken, error)
+	TokenRequired() bool
+}
+
+type exportTokenService struct {
+	repo repository.ExportTokenRepository
+	// true ならトークン無しのエクスポートを拒否する
+	tokenRequired bool
+}
+
+func NewExportTokenService(repo repository.ExportTokenRepository) ExportTokenService {
+	return &exportTokenService{
+		repo:          repo,
+		tokenRequired: os.Getenv("WHITELIST_EXPORT_REQUIRE_TOKEN") == "true",
+	}
+}
+
+// ワールド用のトークンと署名用シークレットを発行する
+func (s *exportTokenService) Issue(ctx context.Context, worldName string) (*models.IssuedExportToken, error) {
+	worldName = strings.TrimSpace(worldName)
+	if worldName == "" || len(worldName) > 128 {
+		return nil, ErrInvalidArgument
+	}
+
+	token, err := randomHex(24)
+	if err != nil {
+		return nil, err
+	}
+	token = exportTokenPrefix + token
+
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &models.ExportToken{
+		WorldName:   worldName,
+		TokenPrefix: token[:len(exportTokenPrefix)+8],
+		Secret:      secret,
+	}
+	if err := s.repo.Create(ctx, t, hashExportToken(token)); err != nil {
+		return nil, err
+	}
+
+	return &models.IssuedExportToken{
+		ExportToken: *t,
+		Token:       token,
+		Secret:      secret,
+	}, nil
+}
+
+func (s *exportTokenService) List(ctx context.Context) ([]models.ExportToken, error) {
+	return s.repo.List(ctx)
+}
+
+func (s *exportTokenService) Revoke(ctx context.Context, id uint64) error {
+	if id == 0 {
+		return ErrInvalidArgument
+	}
+	ok, err := s.repo.Revoke(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ワールドから来たトークンを照合する。未知 or 失効済みなら ErrInvalidToken。
+func (s *exportTokenService) Authenticate(ctx context.Context, token string) (*models.ExportToken, error) {
+	token = strings.TrimSpace(token)
+	if !strings.HasPrefix(token, exportTokenPrefix) {
+		return nil, ErrInvalidToken
+	}
+
+	t, err := s.repo.GetByTokenHash(ctx, hashExportToken(token))
+	if err != nil {
+		return nil, err
+	}
+	if t == nil || t.RevokedAt != nil {
+		return nil, ErrInvalidToken
+	}
+
+	// 最終利用時刻は監視用なので失敗しても配信は止めない
+	if err := s.repo.TouchLastUsed(ctx, t.ID); err != nil {
+		log.Printf("export token touch failed: id=%d err=%+v", t.ID, err)
+	}
+	now := time.Now()
+	t.LastUsedAt = &now
+
+	return t, nil
+}
+
+// WHITELIST_EXPORT_REQUIRE_TOKEN=true なら公開エクスポートにもトークン必須
+func (s *exportTokenService) TokenRequired() bool {
+	return s.tokenRequired
+}
+
+// DB にはトークンそのものではなく SHA-256 を保存する
+func hashExportToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
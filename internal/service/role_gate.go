@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrMissingRole          = errors.New("required discord role missing")
+	ErrRoleCheckUnavailable = errors.New("discord role check unavailable")
+)
+
+// サーバーのメンバーが持っているロールID を引く。discord.Session が満たす。
+// サーバーにいなければ nil, nil。
+type MemberRoleLookup interface {
+	GuildMemberRoles(guildID, userID string) ([]string, error)
+}
+
+// 本人による登録に必要な Discord ロール（サーバーごと）
+type RoleGate interface {
+	// どこかのサーバーで必要ロールが設定されているか
+	Enabled() bool
+	// 必要ロールが設定されているサーバーの ID（昇順）
+	Guilds() []string
+	// そのサーバーで必要なロールID。設定が無ければ空。
+	RequiredRoles(guildID string) []string
+	// Interaction のメンバー情報（i.Member.Roles）で判定する
+	AllowMember(guildID string, roles []string) bool
+	// Discord ID だけで判定する（HTTP 経由の登録用）。設定のあるサーバーのどれかで必要ロールを持っていれば OK。
+	// 持っていなければ ErrMissingRole、Bot が動いていなくて確認できなければ ErrRoleCheckUnavailable。
+	CheckDiscordUser(ctx context.Context, discordID string) error
+}
+
+type roleGate struct {
+	// guild ID → 必要なロールID（どれか1つ持っていればよい）
+	required map[string][]string
+	members  MemberRoleLookup
+}
+
+// DISCORD_REQUIRED_ROLE_IDS で設定する。
+// 形式: <guild_id>:<role_id>,<role_id>;<guild_id>:<role_id>
+// 例: 123456789012345678:111111111111111111,222222222222222222
+// members は Bot が無効なら nil でよい（その場合 HTTP 経由の登録は確認できないので弾く）。
+func NewRoleGate(members MemberRoleLookup) RoleGate {
+	return &roleGate{
+		required: parseRequiredRoles(os.Getenv("DISCORD_REQUIRED_ROLE_IDS")),
+		members:  members,
+	}
+}
+
+func parseRequiredRoles(v string) map[string][]string {
+	required := make(map[string][]string)
+	for _, entry := range strings.Split(v, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		guildID, roles, ok := strings.Cut(entry, ":")
+		guildID = strings.TrimSpace(guildID)
+		if !ok || guildID == "" {
+			log.Printf("invalid DISCORD_REQUIRED_ROLE_IDS entry %q, skipped", entry)
+			continue
+		}
+		for _, roleID := range strings.Split(roles, ",") {
+			if roleID = strings.TrimSpace(roleID); roleID != "" {
+				required[guildID] = append(required[guildID], roleID)
+			}
+		}
+	}
+	return required
+}
+
+func (g *roleGate) Enabled() bool {
+	return len(g.required) > 0
+}
+
+func (g *roleGate) Guilds() []string {
+	return slices.Sorted(maps.Keys(g.required))
+}
+
+func (g *roleGate) RequiredRoles(guildID string) []string {
+	return g.required[guildID]
+}
+
+func (g *roleGate) AllowMember(guildID string, roles []string) bool {
+	required := g.required[guildID]
+	if len(required) == 0 {
+		return true
+	}
+	for _, roleID := range roles {
+		if slices.Contains(required, roleID) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *roleGate) CheckDiscordUser(ctx context.Context, discordID string) error {
+	if !g.Enabled() {
+		return nil
+	}
+	if g.members == nil {
+		return ErrRoleCheckUnavailable
+	}
+
+	for guildID := range g.required {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		roles, err := g.members.GuildMemberRoles(guildID, discordID)
+		if err != nil {
+			return err
+		}
+		if g.AllowMember(guildID, roles) {
+			return nil
+		}
+	}
+	return ErrMissingRole
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"backend/internal/models"
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// 監査ログの action 値
+const (
+	AuditActionRegister   = "register"
+	AuditActionUpdate     = "update"
+	AuditActionRemove     = "remove"
+	AuditActionRestore    = "restore"
+	AuditActionExpire     = "expire"
+	AuditActionSetExpiry  = "set_expiry"
+	AuditActionSetNote    = "set_note"
+	AuditActionResync     = "resync"
+	AuditActionVerify     = "verify"
+	AuditActionUnverified = "unverified_expire"
+	AuditActionVerifyLost = "verify_lost" // 同じ VRChat アカウントを他の人が先に本人確認した
+	AuditActionLeftGuild  = "left_guild"  // Discord サーバーを抜けた
+	AuditActionBanned     = "banned"      // Discord サーバーから BAN された
+	AuditActionDenied     = "denied"      // 登録禁止リストに載ったので外された
+	AuditActionDenyAdd    = "deny_add"
+	AuditActionDenyRemove = "deny_remove"
+	AuditActionListAdd    = "list_add"
+	AuditActionListRemove = "list_remove"
+
+	AuditActionWindowSet            = "window_set"
+	AuditActionWindowOverrideAdd    = "window_override_add"
+	AuditActionWindowOverrideRemove = "window_override_remove"
+	AuditActionWaitlistAdd          = "waitlist_add"
+	AuditActionWaitlistRemove       = "waitlist_remove"
+)
+
+// 監査ログの1ページあたり件数
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 200
+)
+
+// 監査ログの before / after に残す紐付けのスナップショット
+type whitelistSnapshot struct {
+	VRCUserID      string     `json:"vrc_user_id"`
+	VRCDisplayName string     `json:"vrc_display_name"`
+	VRCAvatarURL   string     `json:"vrc_avatar_url,omitempty"`
+	Note           string     `json:"note,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	VerifiedAt     *time.Time `json:"verified_at,omitempty"`
+}
+
+// nil（紐付け無し）なら NULL として残す
+func snapshotOf(u *models.WhitelistUser) json.RawMessage {
+	if u == nil {
+		return nil
+	}
+	b, _ := json.Marshal(whitelistSnapshot{
+		VRCUserID:      u.VRCUserID,
+		VRCDisplayName: u.VRCDisplayName,
+		VRCAvatarURL:   u.VRCAvatarURL,
+		Note:           u.Note,
+		ExpiresAt:      u.ExpiresAt,
+		VerifiedAt:     u.VerifiedAt,
+	})
+	return b
+}
+
+// リスト所属の変更は {"list": "<name>"} で残す
+func listSnapshot(listName string) json.RawMessage {
+	b, _ := json.Marshal(map[string]string{"list": listName})
+	return b
+}
+
+// 監査ログを1件追記する。actor / source / request ID は context から取る。
+// 変更と同じ s.tx.WithinTx の中で呼び、失敗したら変更ごとロールバックさせる。
+func (s *whitelistService) recordAudit(ctx context.Context, action, discordID string, before, after json.RawMessage) error {
+	actor := ActorFrom(ctx)
+	a := &models.WhitelistAudit{
+		Action:        action,
+		DiscordUserID: discordID,
+		Actor:         actor.ID,
+		Source:        actor.Source,
+		Before:        before,
+		After:         after,
+		RequestID:     actor.RequestID,
+	}
+	return s.auditRepo.Append(ctx, a)
+}
+
+// 監査ログを新しい順に返す。Limit は 1〜200 に丸める（0 なら 50）。
+func (s *whitelistService) ListAudit(ctx context.Context, f models.WhitelistAuditFilter) (*models.WhitelistAuditPage, error) {
+	if f.Limit <= 0 {
+		f.Limit = defaultAuditLimit
+	}
+	if f.Limit > maxAuditLimit {
+		f.Limit = maxAuditLimit
+	}
+
+	// 1件多く取って、続きがあるかを判定する
+	limit := f.Limit
+	f.Limit = limit + 1
+	entries, err := s.auditRepo.List(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.WhitelistAuditPage{Items: entries}
+	if len(entries) > limit {
+		page.Items = entries[:limit]
+		page.NextBeforeID = page.Items[limit-1].ID
+	}
+	return page, nil
+}
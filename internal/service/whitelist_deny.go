@@ -0,0 +1,159 @@
+package service
+
+import (
+	"backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+var ErrDenied = errors.New("account is on the deny list")
+
+// 登録禁止の理由の最大文字数（whitelist_denylist.reason の VARCHAR(255) に合わせる）
+const maxDenyReasonLength = 255
+
+// 監査ログに残す登録禁止リストのスナップショット
+func denySnapshot(d *models.WhitelistDeny) json.RawMessage {
+	b, _ := json.Marshal(d)
+	return b
+}
+
+// discordID か VRChat アカウントが登録禁止なら ErrDenied
+func (s *whitelistService) checkDenied(ctx context.Context, discordID, vrcUserID string) error {
+	d, err := s.denyRepo.Find(ctx, discordID, vrcUserID)
+	if err != nil {
+		return err
+	}
+	if d != nil {
+		return ErrDenied
+	}
+	return nil
+}
+
+// Discord ID と VRChat アカウント（表示名・usr_ ID・プロフィールURL）のどちらか、または両方を登録禁止にする。
+// Discord 側と VRChat 側は別の行で載せ、既に載っている側は飛ばす。追加した行を返し、両方とも載っていれば ErrAlreadyExists。
+// 今ある紐付けは同じトランザクションで外す（論理削除。ただし禁止を解くまで restore も再登録もできない）。
+// 追加した管理者は context の Actor から取る。
+func (s *whitelistService) DenyAccount(ctx context.Context, discordID, vrcUser, reason string) ([]models.WhitelistDeny, error) {
+	discordID = strings.TrimSpace(discordID)
+	vrcUser = strings.TrimSpace(vrcUser)
+	reason = strings.TrimSpace(reason)
+	if (discordID == "" && vrcUser == "") || utf8.RuneCountInString(reason) > maxDenyReasonLength {
+		return nil, ErrInvalidArgument
+	}
+
+	var vrcUserID string
+	if vrcUser != "" {
+		user, err := s.resolveVRCUser(ctx, vrcUser)
+		if err != nil {
+			return nil, err
+		}
+		vrcUserID = user.ID
+	}
+
+	var (
+		added   []models.WhitelistDeny
+		removed []string
+	)
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		// その VRChat アカウントを使っている紐付け（本人確認待ちも含む）は全部外す
+		var links []models.WhitelistUser
+		if vrcUserID != "" {
+			var err error
+			if links, err = s.repo.ListByVRCUserID(ctx, vrcUserID); err != nil {
+				return err
+			}
+		}
+		// 監査ログは Discord ID ごとに残すので、VRChat 側の行は確認済みの持ち主（いなければ最初に登録した人）に付ける
+		vrcAuditID := discordID
+		for _, link := range links {
+			if vrcAuditID == "" || (discordID == "" && link.VerifiedAt != nil) {
+				vrcAuditID = link.DiscordUserID
+			}
+		}
+
+		targets := []struct {
+			deny    models.WhitelistDeny
+			auditID string
+		}{
+			{models.WhitelistDeny{DiscordUserID: discordID}, discordID},
+			{models.WhitelistDeny{VRCUserID: vrcUserID}, vrcAuditID},
+		}
+		for _, t := range targets {
+			if t.deny.DiscordUserID == "" && t.deny.VRCUserID == "" {
+				continue
+			}
+			d := t.deny
+			d.Reason = reason
+			d.AddedBy = ActorFrom(ctx).ID
+			ok, err := s.denyRepo.Add(ctx, &d)
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+			if err := s.recordAudit(ctx, AuditActionDenyAdd, t.auditID, nil, denySnapshot(&d)); err != nil {
+				return err
+			}
+			added = append(added, d)
+		}
+		if len(added) == 0 {
+			return ErrAlreadyExists
+		}
+
+		ids := make([]string, 0, len(links)+1)
+		for _, link := range links {
+			ids = append(ids, link.DiscordUserID)
+		}
+		if discordID != "" {
+			ids = append(ids, discordID)
+		}
+		for _, id := range ids {
+			ok, _, err := s.unlinkDiscord(ctx, id, AuditActionDenied)
+			if err != nil {
+				return err
+			}
+			if ok {
+				removed = append(removed, id)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// ロールの付け外しと繰り上げはコミットしてから
+	for _, id := range removed {
+		s.syncRole(ctx, id, false)
+	}
+	if len(removed) > 0 {
+		s.promoteAfterRemoval(ctx)
+	}
+	return added, nil
+}
+
+func (s *whitelistService) ListDenied(ctx context.Context) ([]models.WhitelistDeny, error) {
+	return s.denyRepo.List(ctx)
+}
+
+// 登録禁止を解く。外した紐付けは戻さない（必要なら restore する）。無ければ ErrNotFound。
+func (s *whitelistService) RemoveDeny(ctx context.Context, id uint64) error {
+	if id == 0 {
+		return ErrInvalidArgument
+	}
+
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		before, err := s.denyRepo.Delete(ctx, id)
+		if err != nil {
+			return err
+		}
+		if before == nil {
+			return ErrNotFound
+		}
+		return s.recordAudit(ctx, AuditActionDenyRemove, before.DiscordUserID, denySnapshot(before), nil)
+	})
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"backend/internal/models"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// スタッフ向けエクスポートの形式
+const (
+	DumpFormatCSV    = "csv"
+	DumpFormatNDJSON = "ndjson"
+)
+
+// CSV のヘッダー。並びは whitelistDumpRecord と揃えること。
+var whitelistDumpHeader = []string{
+	"id",
+	"discord_user_id",
+	"vrc_user_id",
+	"vrc_display_name",
+	"vrc_avatar_url",
+	"note",
+	"expires_at",
+	"verified_at",
+	"created_at",
+	"updated_at",
+}
+
+// スタッフがスプレッドシートで見るためのエクスポート。
+// DB から1行読むごとに w に書くので、件数が多くても全件をメモリに載せない。
+// format が DumpFormatCSV / DumpFormatNDJSON 以外なら ErrInvalidArgument。
+func (s *whitelistService) DumpWhitelist(ctx context.Context, w io.Writer, format string) error {
+	switch format {
+	case DumpFormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(whitelistDumpHeader); err != nil {
+			return err
+		}
+		err := s.repo.Each(ctx, func(u *models.WhitelistUser) error {
+			return cw.Write(whitelistDumpRecord(u))
+		})
+		if err != nil {
+			return err
+		}
+		cw.Flush()
+		return cw.Error()
+
+	case DumpFormatNDJSON:
+		// Encoder は1件ごとに改行付きで書き出す
+		enc := json.NewEncoder(w)
+		return s.repo.Each(ctx, func(u *models.WhitelistUser) error {
+			return enc.Encode(u)
+		})
+
+	default:
+		return ErrInvalidArgument
+	}
+}
+
+// 日時は RFC3339（UTC）。期限なし・本人確認待ちは空欄。
+func whitelistDumpRecord(u *models.WhitelistUser) []string {
+	return []string{
+		strconv.FormatUint(u.ID, 10),
+		u.DiscordUserID,
+		u.VRCUserID,
+		escapeCSVFormula(u.VRCDisplayName),
+		u.VRCAvatarURL,
+		escapeCSVFormula(u.Note),
+		formatDumpTime(u.ExpiresAt),
+		formatDumpTime(u.VerifiedAt),
+		u.CreatedAt.UTC().Format(time.RFC3339),
+		u.UpdatedAt.UTC().Format(time.RFC3339),
+	}
+}
+
+// 表示名・メモは本人や管理者が自由に書けるので、スプレッドシートで数式として実行されないよう
+// = + - @ タブ CR で始まるセルは先頭に ' を付ける（CSV インジェクション対策）
+func escapeCSVFormula(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
+
+func formatDumpTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"backend/internal/models"
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// 1回のインポートで受け付ける最大行数（VRChat API を1行ずつ叩くので多すぎると終わらない）
+const maxImportRows = 500
+
+// Discord のユーザーID（snowflake）。ユーザー名や @メンションが書かれていたら弾く
+var discordIDPattern = regexp.MustCompile(`^[0-9]{17,20}$`)
+
+// CSV でまとめてホワイトリストに登録する。行ごとの判定は RegisterDiscordVRC と同じ。
+// 1行目はヘッダーで、"discord" を含む列を Discord ID、"vrchat" か "vrc" を含む列を VRChat 名として読む
+// （Google フォームの「Discord ID」「VRChat名」のような列名をそのまま使える）。
+// dryRun なら何も書き込まずに結果だけ返す。
+// ヘッダーが読めない・行数が多すぎるときは ErrInvalidArgument。
+func (s *whitelistService) ImportWhitelistCSV(ctx context.Context, r io.Reader, dryRun bool) (*models.WhitelistImportReport, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1 // フォームの出力は列数が揃っていないことがある
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, ErrInvalidArgument
+	}
+	discordCol, vrcCol := importColumns(header)
+	if discordCol < 0 || vrcCol < 0 {
+		return nil, ErrInvalidArgument
+	}
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, ErrInvalidArgument
+	}
+	if len(records) > maxImportRows {
+		return nil, ErrInvalidArgument
+	}
+
+	report := &models.WhitelistImportReport{
+		DryRun:  dryRun,
+		Rows:    make([]models.WhitelistImportRow, 0, len(records)),
+		Summary: map[string]int{},
+	}
+
+	// ドライランでは DB が変わらないので、CSV 内での重複はここで追いかける
+	seenDiscord := map[string]bool{} // この CSV で登録される Discord ID
+	seenVRC := map[string]string{}   // VRC userID → この CSV で紐付ける Discord ID
+
+	for idx, rec := range records {
+		row := models.WhitelistImportRow{
+			Line:           idx + 2,
+			DiscordUserID:  strings.TrimSpace(field(rec, discordCol)),
+			VRCDisplayName: strings.TrimSpace(field(rec, vrcCol)),
+		}
+		// 完全に空の行は飛ばす
+		if row.DiscordUserID == "" && row.VRCDisplayName == "" {
+			continue
+		}
+		s.importRow(ctx, &row, dryRun, seenDiscord, seenVRC)
+
+		report.Rows = append(report.Rows, row)
+		report.Summary[row.Status]++
+	}
+	return report, nil
+}
+
+// 1行分を解決して row.Status を埋める
+func (s *whitelistService) importRow(
+	ctx context.Context,
+	row *models.WhitelistImportRow,
+	dryRun bool,
+	seenDiscord map[string]bool,
+	seenVRC map[string]string,
+) {
+	if row.DiscordUserID == "" || row.VRCDisplayName == "" {
+		row.Status = models.ImportStatusInvalid
+		row.Message = "discord id and vrchat name are required"
+		return
+	}
+	if !discordIDPattern.MatchString(row.DiscordUserID) {
+		row.Status = models.ImportStatusInvalid
+		row.Message = "discord id must be a numeric user id"
+		return
+	}
+
+	// VRChat 名の列に ID やプロフィールURL が書かれていてもそのまま使える
+	user, err := s.resolveVRCUser(ctx, row.VRCDisplayName)
+	switch {
+	case errors.Is(err, ErrNoExactMatch), errors.Is(err, ErrVRCUserNotFound):
+		row.Status = models.ImportStatusNoMatch
+		return
+	case errors.Is(err, ErrMultipleExactMatch):
+		row.Status = models.ImportStatusMultipleMatches
+		return
+	case err != nil:
+		log.Printf("whitelist import: vrchat search failed: line=%d err=%+v", row.Line, err)
+		row.Status = models.ImportStatusError
+		row.Message = "vrchat search failed"
+		return
+	}
+	row.VRCUserID = user.ID
+
+	if other, ok := seenVRC[user.ID]; ok && other != row.DiscordUserID {
+		row.Status = models.ImportStatusConflict
+		row.Message = "same vrchat account appears earlier in this csv"
+		return
+	}
+
+	var created bool
+	if dryRun {
+		var existing *models.WhitelistUser
+		existing, err = s.checkLink(ctx, row.DiscordUserID, user.ID)
+		created = existing == nil && !seenDiscord[row.DiscordUserID]
+	} else {
+		created, err = s.linkVRCUser(ctx, row.DiscordUserID, user, true)
+	}
+	switch {
+	case errors.Is(err, ErrAlreadyExists):
+		row.Status = models.ImportStatusConflict
+		return
+	case errors.Is(err, ErrDenied):
+		row.Status = models.ImportStatusDenied
+		return
+	case err != nil:
+		log.Printf("whitelist import: link failed: line=%d err=%+v", row.Line, err)
+		row.Status = models.ImportStatusError
+		row.Message = "internal error"
+		return
+	}
+
+	seenDiscord[row.DiscordUserID] = true
+	seenVRC[user.ID] = row.DiscordUserID
+	if created {
+		row.Status = models.ImportStatusCreated
+	} else {
+		row.Status = models.ImportStatusUpdated
+	}
+}
+
+// ヘッダーから Discord ID / VRChat 名の列番号を探す。見つからなければ -1。
+func importColumns(header []string) (discordCol, vrcCol int) {
+	discordCol, vrcCol = -1, -1
+	for i, h := range header {
+		h = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\uFEFF"))) // Excel 由来の BOM
+		switch {
+		case discordCol < 0 && strings.Contains(h, "discord"):
+			discordCol = i
+		case vrcCol < 0 && (strings.Contains(h, "vrchat") || strings.Contains(h, "vrc")):
+			vrcCol = i
+		}
+	}
+	return discordCol, vrcCol
+}
+
+// 列が足りない行は空文字として扱う
+func field(rec []string, i int) string {
+	if i < len(rec) {
+		return rec[i]
+	}
+	return ""
+}
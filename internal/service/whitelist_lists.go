@@ -0,0 +1,141 @@
+package service
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrListNotFound  = errors.New("whitelist list not found")
+	ErrNotRegistered = errors.New("discord user has no vrchat link")
+)
+
+// URL（/whitelist/lists/:name/export.txt）にも使うので英小文字・数字・_- だけ
+var listNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+func (s *whitelistService) CreateList(ctx context.Context, name, description string, selfService bool) (*models.WhitelistList, error) {
+	name = strings.TrimSpace(name)
+	description = strings.TrimSpace(description)
+	if !listNamePattern.MatchString(name) || len(description) > 255 {
+		return nil, ErrInvalidArgument
+	}
+
+	l := &models.WhitelistList{
+		Name:        name,
+		Description: description,
+		SelfService: selfService,
+	}
+	if err := s.listRepo.Create(ctx, l); err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return nil, ErrAlreadyExists
+		}
+		return nil, err
+	}
+	return l, nil
+}
+
+func (s *whitelistService) GetLists(ctx context.Context) ([]models.WhitelistList, error) {
+	return s.listRepo.List(ctx)
+}
+
+// この Discord ユーザーが所属しているリスト。未登録なら空。
+func (s *whitelistService) GetListsByDiscord(ctx context.Context, discordID string) ([]models.WhitelistList, error) {
+	if discordID == "" {
+		return nil, ErrInvalidArgument
+	}
+	link, err := s.repo.GetByDiscordID(ctx, discordID)
+	if err != nil {
+		return nil, err
+	}
+	if link == nil {
+		return []models.WhitelistList{}, nil
+	}
+	return s.listRepo.ListByWhitelistUserID(ctx, link.ID)
+}
+
+// 既存の VRChat 紐付けをリストに追加する。紐付けが無ければ ErrNotRegistered。
+func (s *whitelistService) AddToList(ctx context.Context, listName, discordID string) (bool, error) {
+	list, link, err := s.resolveMembership(ctx, listName, discordID)
+	if err != nil {
+		return false, err
+	}
+	added := false
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		if added, err = s.listRepo.AddMember(ctx, list.ID, link.ID); err != nil || !added {
+			return err
+		}
+		return s.recordAudit(ctx, AuditActionListAdd, link.DiscordUserID, nil, listSnapshot(list.Name))
+	})
+	return added, err
+}
+
+func (s *whitelistService) RemoveFromList(ctx context.Context, listName, discordID string) (bool, error) {
+	list, link, err := s.resolveMembership(ctx, listName, discordID)
+	if err != nil {
+		return false, err
+	}
+	removed := false
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		if removed, err = s.listRepo.RemoveMember(ctx, list.ID, link.ID); err != nil || !removed {
+			return err
+		}
+		return s.recordAudit(ctx, AuditActionListRemove, link.DiscordUserID, listSnapshot(list.Name), nil)
+	})
+	return removed, err
+}
+
+// リストごとのエクスポート用に所属者を登録順で返す
+func (s *whitelistService) ListWhitelistByList(ctx context.Context, listName string) ([]models.WhitelistUser, error) {
+	list, err := s.getList(ctx, listName)
+	if err != nil {
+		return nil, err
+	}
+	return s.listRepo.ListMembers(ctx, list.ID)
+}
+
+func (s *whitelistService) getList(ctx context.Context, listName string) (*models.WhitelistList, error) {
+	listName = strings.TrimSpace(listName)
+	if listName == "" {
+		return nil, ErrInvalidArgument
+	}
+	list, err := s.listRepo.GetByName(ctx, listName)
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		return nil, ErrListNotFound
+	}
+	return list, nil
+}
+
+// リスト名と Discord ID から、リストと紐付けの両方を引く
+func (s *whitelistService) resolveMembership(
+	ctx context.Context,
+	listName string,
+	discordID string,
+) (*models.WhitelistList, *models.WhitelistUser, error) {
+	discordID = strings.TrimSpace(discordID)
+	if discordID == "" {
+		return nil, nil, ErrInvalidArgument
+	}
+
+	list, err := s.getList(ctx, listName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	link, err := s.repo.GetByDiscordID(ctx, discordID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if link == nil {
+		return nil, nil, ErrNotRegistered
+	}
+	return list, link, nil
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// VRChat Image Loader 向けにホワイトリストをピクセルへ詰めた PNG を作る。
+//
+// バイト列のレイアウト（数値はすべてビッグエンディアン）:
+//
+//	offset  size  内容
+//	0       4     マジック "YRWL"
+//	4       1     フォーマットバージョン（現在 1）
+//	5       1     フラグ（bit0: 署名付き）
+//	6       2     予約（0）
+//	8       4     リストのバージョン（whitelist_version.version の下位32bit）
+//	12      4     エントリ数 N
+//	16      8     生成時刻 unix秒（署名付きのときだけ）
+//	...     ...   N 個のエントリ。各エントリは [長さ 1byte][VRC user ID (ASCII)]
+//	...     32    HMAC-SHA256（署名付きのときだけ。先頭からエントリ末尾までに対する署名）
+//
+// ピクセルへの詰め方:
+//   - 1ピクセルに R, G, B の順で 3byte ずつ入れる。A は常に 255。
+//   - 幅は WhitelistPNGWidth 固定、左上から右へ、行が埋まったら次の行へ進む。
+//   - 余ったピクセルは 0 埋め。
+//
+// ワールド側はテクスチャを sRGB 無効・ミップマップ無効・Point フィルタで読むこと。
+const (
+	WhitelistPNGMagic         = "YRWL"
+	WhitelistPNGFormatVersion = 1
+	WhitelistPNGWidth         = 64
+
+	WhitelistPNGFlagSigned = 0x01
+
+	whitelistPNGHeaderSize = 16
+)
+
+// ヘッダ + エントリのバイト列を組み立てる
+func encodeWhitelistPayload(version uint32, vrcUserIDs []string, sig *ExportSignature) ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, whitelistPNGHeaderSize+len(vrcUserIDs)*41))
+
+	var flags byte
+	if sig != nil {
+		flags |= WhitelistPNGFlagSigned
+	}
+
+	buf.WriteString(WhitelistPNGMagic)
+	buf.WriteByte(WhitelistPNGFormatVersion)
+	buf.WriteByte(flags)
+	buf.Write([]byte{0, 0})
+	_ = binary.Write(buf, binary.BigEndian, version)
+	_ = binary.Write(buf, binary.BigEndian, uint32(len(vrcUserIDs)))
+	if sig != nil {
+		_ = binary.Write(buf, binary.BigEndian, uint64(sig.Timestamp.Unix()))
+	}
+
+	for _, id := range vrcUserIDs {
+		// 長さを1byteで持つので 255 を超えるIDは入らない（VRC user ID は最大でも 40 文字程度）
+		if len(id) == 0 || len(id) > 255 {
+			return nil, fmt.Errorf("invalid vrc user id length: %q", id)
+		}
+		buf.WriteByte(byte(len(id)))
+		buf.WriteString(id)
+	}
+
+	if sig != nil {
+		buf.Write(sig.sum(buf.Bytes()))
+	}
+
+	return buf.Bytes(), nil
+}
+
+// バイト列を RGB ピクセルに詰めて PNG にエンコードする。sig が nil なら署名なし。
+func EncodeWhitelistPNG(version uint32, vrcUserIDs []string, sig *ExportSignature) ([]byte, error) {
+	payload, err := encodeWhitelistPayload(version, vrcUserIDs, sig)
+	if err != nil {
+		return nil, err
+	}
+
+	pixels := (len(payload) + 2) / 3
+	height := (pixels + WhitelistPNGWidth - 1) / WhitelistPNGWidth
+	if height == 0 {
+		height = 1
+	}
+
+	// 乗算済みアルファで値が変わらないよう NRGBA + A=255 で書く
+	img := image.NewNRGBA(image.Rect(0, 0, WhitelistPNGWidth, height))
+	for p := 0; p < WhitelistPNGWidth*height; p++ {
+		var rgb [3]byte
+		for k := 0; k < 3; k++ {
+			if idx := p*3 + k; idx < len(payload) {
+				rgb[k] = payload[idx]
+			}
+		}
+		img.SetNRGBA(p%WhitelistPNGWidth, p/WhitelistPNGWidth, color.NRGBA{R: rgb[0], G: rgb[1], B: rgb[2], A: 255})
+	}
+
+	var out bytes.Buffer
+	if err := png.Encode(&out, img); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"backend/internal/models"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// 本人による登録・変更を続けてし過ぎた。いつから試せるかは *RateLimitError で返す。
+var ErrRateLimited = errors.New("too many registration attempts")
+
+type RateLimitError struct {
+	RetryAt time.Time
+	// true なら 1日の変更回数の上限、false なら連続操作の間隔
+	Daily bool
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s (retry at %s)", ErrRateLimited.Error(), e.RetryAt.Format(time.RFC3339))
+}
+
+// errors.Is(err, ErrRateLimited) で判定できるように
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+const (
+	defaultRegisterCooldown   = 30 * time.Second
+	defaultDailyChangeLimit   = 5
+	registerChangeLimitWindow = 24 * time.Hour
+)
+
+// WHITELIST_REGISTER_COOLDOWN（例: 30s, 1m）で、本人が続けて登録・変更できるまでの間隔を変えられる。0 で無効。
+func registerCooldownFromEnv() time.Duration {
+	v := os.Getenv("WHITELIST_REGISTER_COOLDOWN")
+	if v == "" {
+		return defaultRegisterCooldown
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		log.Printf("invalid WHITELIST_REGISTER_COOLDOWN=%q, using %s", v, defaultRegisterCooldown)
+		return defaultRegisterCooldown
+	}
+	return d
+}
+
+// WHITELIST_DAILY_CHANGE_LIMIT で、1日（24時間）に VRChat アカウントを変えられる回数を変えられる。0 で無制限。
+func dailyChangeLimitFromEnv() int {
+	v := os.Getenv("WHITELIST_DAILY_CHANGE_LIMIT")
+	if v == "" {
+		return defaultDailyChangeLimit
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		log.Printf("invalid WHITELIST_DAILY_CHANGE_LIMIT=%q, using %d", v, defaultDailyChangeLimit)
+		return defaultDailyChangeLimit
+	}
+	return n
+}
+
+// 本人が今すぐ登録・変更できるか。できなければ *RateLimitError。見るだけで枠は取らない。
+// 管理者による登録（AdminRegisterDiscordVRC・CSV インポート）と、サーバー管理者本人の登録（Actor.Admin）はここを通らない。
+func (s *whitelistService) CheckRegisterRateLimit(ctx context.Context, discordID string) error {
+	l, err := s.limitRepo.Get(ctx, discordID)
+	if err != nil || l == nil {
+		return err
+	}
+	return s.rateLimitError(l, time.Now())
+}
+
+func (s *whitelistService) rateLimitError(l *models.RegisterLimit, now time.Time) error {
+	// 1日の上限の方が待ち時間が長いので先に見る
+	if s.dailyChangeLimit > 0 && l.ChangeCount >= s.dailyChangeLimit {
+		if resetAt := l.ChangesSince.Add(registerChangeLimitWindow); now.Before(resetAt) {
+			return &RateLimitError{RetryAt: resetAt, Daily: true}
+		}
+	}
+	if s.registerCooldown > 0 {
+		if retryAt := l.LastAttemptAt.Add(s.registerCooldown); now.Before(retryAt) {
+			return &RateLimitError{RetryAt: retryAt}
+		}
+	}
+	return nil
+}
+
+// 回数制限を受けるか。Discord ID を Discord 側が保証している Interaction 経由の本人だけ。
+// HTTP API の Discord ID は本文に書かれたままで確かめられないので、数えると他人の ID で締め出せてしまう。
+func registerLimitApplies(a Actor) bool {
+	switch a.Source {
+	case SourceDiscordButton, SourceDiscordModal, SourceDiscordCommand:
+		return !a.Admin
+	}
+	return false
+}
+
+// VRChat API を叩く前に1回分の枠を取り、取った時刻を返す。
+// 確認と記録を1つの文でやるので、同時に押されても通るのは1件だけ。取れなければ *RateLimitError。
+func (s *whitelistService) reserveRegisterAttempt(ctx context.Context, discordID string) (time.Time, error) {
+	if err := s.CheckRegisterRateLimit(ctx, discordID); err != nil {
+		return time.Time{}, err
+	}
+
+	now := time.Now()
+	ok, err := s.limitRepo.ReserveAttempt(ctx, discordID, now, now.Add(-s.registerCooldown))
+	if err != nil || ok {
+		return now, err
+	}
+	// 先を越された。待ち時間はその記録から出す
+	l, err := s.limitRepo.Get(ctx, discordID)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if l != nil {
+		if err := s.rateLimitError(l, now); err != nil {
+			return time.Time{}, err
+		}
+	}
+	return time.Time{}, &RateLimitError{RetryAt: now.Add(s.registerCooldown)}
+}
+
+// 同名の候補が複数いたときは、候補から選ぶところまでを1回として数えないので枠を返す。
+// 返せなくても登録は止めない。
+func (s *whitelistService) releaseRegisterAttempt(ctx context.Context, discordID string, at time.Time, lookupErr error) {
+	var multi *MultipleMatchError
+	if !errors.As(lookupErr, &multi) {
+		return
+	}
+	if err := s.limitRepo.ReleaseAttempt(ctx, discordID, at, at.Add(-s.registerCooldown)); err != nil {
+		log.Printf("whitelist register limit: release attempt failed (discord_user_id=%s): %+v", discordID, err)
+	}
+}
+
+// VRChat アカウントを変えた（新規登録・付け替え・キャンセル待ちのアカウント変更）回数を数える
+func (s *whitelistService) recordRegisterChange(ctx context.Context, discordID string) {
+	now := time.Now()
+	if err := s.limitRepo.RecordChange(ctx, discordID, now, now.Add(-registerChangeLimitWindow)); err != nil {
+		log.Printf("whitelist register limit: record change failed (discord_user_id=%s): %+v", discordID, err)
+	}
+}
+
+// 今 discordID に紐付いている（無ければキャンセル待ちで並んでいる）VRChat アカウント。どちらも無ければ空。
+func (s *whitelistService) currentVRCUserID(ctx context.Context, discordID string) (string, error) {
+	link, err := s.repo.GetByDiscordID(ctx, discordID)
+	if err != nil {
+		return "", err
+	}
+	if link != nil {
+		return link.VRCUserID, nil
+	}
+	entry, err := s.waitlistRepo.GetByDiscordID(ctx, discordID)
+	if err != nil || entry == nil {
+		return "", err
+	}
+	return entry.VRCUserID, nil
+}
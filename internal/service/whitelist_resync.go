@@ -0,0 +1,50 @@
+package service
+
+import (
+	"backend/internal/models"
+	"context"
+	"strings"
+)
+
+// 表示名の変更履歴の表示件数
+const nameHistoryLimit = 50
+
+// VRChat から u の最新の表示名・アバターを取り直して保存する。
+// 表示名が変わっていればその変更を返す（変わっていなければ nil）。
+// VRChat 側でアカウントが消えていれば ErrVRCUserNotFound。
+func (s *whitelistService) ResyncVRCProfile(ctx context.Context, u models.WhitelistUser) (*models.WhitelistNameChange, error) {
+	latest, err := s.vrchat.GetUserByID(ctx, u.VRCUserID)
+	if err != nil {
+		return nil, err
+	}
+	if latest.DisplayName == u.VRCDisplayName && latest.CurrentAvatarImageURL == u.VRCAvatarURL {
+		return nil, nil
+	}
+
+	var change *models.WhitelistNameChange
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		// その間に別のアカウントへ付け替えられていたら何もしない（nil）
+		var err error
+		if change, err = s.repo.UpdateVRCProfile(ctx, u.ID, u.VRCUserID, latest.DisplayName, latest.CurrentAvatarImageURL); err != nil || change == nil {
+			return err
+		}
+		// 表示名の変更はエクスポートの中身が変わるので監査ログにも残す
+		after := u
+		after.VRCDisplayName = latest.DisplayName
+		after.VRCAvatarURL = latest.CurrentAvatarImageURL
+		return s.recordAudit(ctx, AuditActionResync, u.DiscordUserID, snapshotOf(&u), snapshotOf(&after))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return change, nil
+}
+
+// 表示名の変更履歴を新しい順に返す
+func (s *whitelistService) ListNameHistory(ctx context.Context, discordID string) ([]models.WhitelistNameChange, error) {
+	discordID = strings.TrimSpace(discordID)
+	if discordID == "" {
+		return nil, ErrInvalidArgument
+	}
+	return s.repo.ListNameHistory(ctx, discordID, nameHistoryLimit)
+}
@@ -0,0 +1,22 @@
+package service
+
+import (
+	"context"
+	"log"
+)
+
+// ホワイトリストに載っている（本人確認済みで削除されていない）人に Discord のロールを付け外しする。
+// discord.RoleSyncer が満たす。
+type WhitelistRoleSyncer interface {
+	SetWhitelisted(ctx context.Context, discordID string, whitelisted bool) error
+}
+
+// ロールの付け外し。失敗してもホワイトリスト自体の変更は取り消さない（ずれは reconcile で直す）。
+func (s *whitelistService) syncRole(ctx context.Context, discordID string, whitelisted bool) {
+	if s.roleSyncer == nil {
+		return
+	}
+	if err := s.roleSyncer.SetWhitelisted(ctx, discordID, whitelisted); err != nil {
+		log.Printf("whitelist role sync failed (discord_user_id=%s, whitelisted=%t): %+v", discordID, whitelisted, err)
+	}
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"backend/internal/models"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 管理画面向け一覧の1ページあたり件数
+const (
+	defaultSearchLimit = 50
+	maxSearchLimit     = 200
+)
+
+// 管理画面向けの一覧・検索。Limit は 1〜200 に丸める（0 なら 50）。
+// 不明な並び順や、壊れている・並び順と合わないカーソルは ErrInvalidArgument。
+func (s *whitelistService) SearchWhitelist(ctx context.Context, f models.WhitelistUserFilter) (*models.WhitelistUserPage, error) {
+	f.DiscordUserID = strings.TrimSpace(f.DiscordUserID)
+	f.VRCUserID = strings.TrimSpace(f.VRCUserID)
+	f.VRCDisplayName = strings.TrimSpace(f.VRCDisplayName)
+
+	switch f.Sort {
+	case "":
+		f.Sort = models.WhitelistSortCreated
+	case models.WhitelistSortCreated, models.WhitelistSortUpdated:
+	default:
+		return nil, ErrInvalidArgument
+	}
+
+	if f.Limit <= 0 {
+		f.Limit = defaultSearchLimit
+	}
+	if f.Limit > maxSearchLimit {
+		f.Limit = maxSearchLimit
+	}
+
+	var after *models.WhitelistUserCursor
+	if f.Cursor != "" {
+		c, err := decodeWhitelistCursor(f.Cursor, f.Sort)
+		if err != nil {
+			return nil, ErrInvalidArgument
+		}
+		after = c
+	}
+
+	// 1件多く取って、続きがあるかを判定する
+	limit := f.Limit
+	f.Limit = limit + 1
+	users, err := s.repo.Search(ctx, f, after)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.WhitelistUserPage{Items: users}
+	if len(users) > limit {
+		page.Items = users[:limit]
+		page.NextCursor = encodeWhitelistCursor(f.Sort, &page.Items[limit-1])
+	}
+	return page, nil
+}
+
+// VRChat のユーザーID・プロフィールURL（完全一致）か表示名（部分一致）で紐付けを探す。
+// 管理者の問い合わせ用なので1ページ分だけ、更新が新しい順に返す。
+func (s *whitelistService) LookupVRC(ctx context.Context, query string, limit int) ([]models.WhitelistUser, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, ErrInvalidArgument
+	}
+
+	f := models.WhitelistUserFilter{Sort: models.WhitelistSortUpdated, Limit: limit}
+	if id, ok := parseVRCUserRef(query); ok {
+		f.VRCUserID = id
+	} else {
+		f.VRCDisplayName = query
+	}
+
+	page, err := s.SearchWhitelist(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	return page.Items, nil
+}
+
+// カーソルは「並び順:時刻(unixマイクロ秒):id」を base64url にしたもの。
+// 中身は不透明な値として扱ってもらう前提で、並び順が違うカーソルは弾く。
+func encodeWhitelistCursor(sort string, u *models.WhitelistUser) string {
+	at := u.CreatedAt
+	if sort == models.WhitelistSortUpdated {
+		at = u.UpdatedAt
+	}
+	raw := fmt.Sprintf("%s:%d:%d", sort, at.UnixMicro(), u.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeWhitelistCursor(cursor, sort string) (*models.WhitelistUserCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+	parts := strings.Split(string(b), ":")
+	if len(parts) != 3 || parts[0] != sort {
+		return nil, errors.New("cursor does not match sort")
+	}
+	micros, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	id, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	return &models.WhitelistUserCursor{At: time.UnixMicro(micros), ID: id}, nil
+}
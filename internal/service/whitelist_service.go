@@ -1,121 +1,634 @@
-package service
-
-import (
-	"backend/internal/models"
-	"backend/internal/repository"
-	"context"
-	"errors"
-	"strings"
-)
-
-var (
-	ErrInvalidArgument = errors.New("invalid argument")
-	ErrAlreadyExists   = errors.New("already exists") // 他人がそのVRC IDを使用
-)
-
-type WhitelistService interface {
-	RegisterDiscordVRC(ctx context.Context, discordID, vrcDisplayName string) (created bool, err error)
-	GetDiscordVRC(ctx context.Context, discordID string) (*models.WhitelistUser, error)
-	IsAllowedByDiscord(ctx context.Context, discordID string) (bool, error)
-	IsAllowedByVRCUserID(ctx context.Context, vrcUserID string) (bool, error)
-	RemoveDiscord(ctx context.Context, discordID string) error
-}
-
-type whitelistService struct {
-	repo   repository.WhitelistRepository
-	vrchat VRChatClient
-}
-
-func NewWhitelistService(repo repository.WhitelistRepository, vrchat VRChatClient) WhitelistService {
-	return &whitelistService{
-		repo:   repo,
-		vrchat: vrchat,
-	}
-}
-
-func (s *whitelistService) RegisterDiscordVRC(
-	ctx context.Context,
-	discordID string,
-	vrcDisplayName string,
-) (bool, error) {
-	// 空白削除
-	discordID = strings.TrimSpace(discordID)
-	vrcDisplayName = strings.TrimSpace(vrcDisplayName)
-	// どっちか空
-	if discordID == "" || vrcDisplayName == "" {
-		return false, ErrInvalidArgument
-	}
-
-	// 1. VRChat APIで完全一致検索
-	user, err := s.vrchat.SearchExactUserByDisplayName(ctx, vrcDisplayName)
-	if err != nil {
-		// ErrNoExactMatch / ErrMultipleExactMatch はそのまま上に返してハンドラー側で文言出す
-		if errors.Is(err, ErrNoExactMatch) || errors.Is(err, ErrMultipleExactMatch) {
-			return false, err
-		}
-		return false, err
-	}
-
-	// 2. その VRC userID が他人に使われていないか確認
-	existingByVRC, err := s.repo.GetByVRCUserID(ctx, user.ID)
-	// 使われていたらエラー
-	if err != nil {
-		return false, err
-	}
-	// 他人のVRCアカウントは使っちゃダメ
-	if existingByVRC != nil && existingByVRC.DiscordUserID != discordID {
-		return false, ErrAlreadyExists
-	}
-
-	// 3. Discordユーザの既存レコード確認
-	existingByDiscord, err := s.repo.GetByDiscordID(ctx, discordID)
-	// このDiscordユーザーは、既に何かVRCアカウントと紐づいてたらエラー
-	if err != nil {
-		return false, err
-	}
-
-	u := &models.WhitelistUser{
-		DiscordUserID:  discordID,
-		VRCUserID:      user.ID,
-		VRCDisplayName: user.DisplayName,
-		VRCAvatarURL:   user.CurrentAvatarImageURL,
-		Note:           "",
-	}
-
-	// 4. Upsert で (discordID, userID) を保存
-	if err := s.repo.Upsert(ctx, u); err != nil {
-		return false, err
-	}
-
-	// created = true → 新規, false → 新規ではなく更新
-	created := (existingByDiscord == nil)
-	return created, nil
-}
-
-func (s *whitelistService) GetDiscordVRC(ctx context.Context, discordID string) (*models.WhitelistUser, error) {
-	if discordID == "" {
-		return nil, ErrInvalidArgument
-	}
-	return s.repo.GetByDiscordID(ctx, discordID)
-}
-
-func (s *whitelistService) IsAllowedByDiscord(ctx context.Context, discordID string) (bool, error) {
-	if discordID == "" {
-		return false, ErrInvalidArgument
-	}
-	return s.repo.ExistsByDiscordID(ctx, discordID)
-}
-
-func (s *whitelistService) IsAllowedByVRCUserID(ctx context.Context, vrcUserID string) (bool, error) {
-	if vrcUserID == "" {
-		return false, ErrInvalidArgument
-	}
-	return s.repo.ExistsByVRCUserID(ctx, vrcUserID)
-}
-
-func (s *whitelistService) RemoveDiscord(ctx context.Context, discordID string) error {
-	if discordID == "" {
-		return ErrInvalidArgument
-	}
-	return s.repo.RemoveByDiscordID(ctx, discordID)
-}
+package service
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// 管理者用メモの最大文字数（whitelist_users.note の VARCHAR(255) に合わせる）
+const maxNoteLength = 255
+
+var (
+	ErrInvalidArgument = errors.New("invalid argument")
+	ErrAlreadyExists   = errors.New("already exists") // 他人がそのVRC IDを使用
+)
+
+type WhitelistService interface {
+	RegisterDiscordVRC(ctx context.Context, discordID, vrcUser string) (created bool, err error)
+	AdminRegisterDiscordVRC(ctx context.Context, discordID, vrcUser string) (created bool, err error)
+	GetDiscordVRC(ctx context.Context, discordID string) (*models.WhitelistUser, error)
+	IsAllowedByDiscord(ctx context.Context, discordID string) (bool, error)
+	IsAllowedByVRCUserID(ctx context.Context, vrcUserID string) (bool, error)
+	RemoveDiscord(ctx context.Context, discordID string) error
+	RemoveDiscordForReason(ctx context.Context, discordID, reason string) (removed bool, err error)
+	ListWhitelist(ctx context.Context) ([]models.WhitelistUser, error)
+	GetWhitelistVersion(ctx context.Context) (*models.WhitelistVersion, error)
+
+	// 管理画面向けの一覧・検索（whitelist_search.go）
+	SearchWhitelist(ctx context.Context, f models.WhitelistUserFilter) (*models.WhitelistUserPage, error)
+	LookupVRC(ctx context.Context, query string, limit int) ([]models.WhitelistUser, error)
+
+	// CSV 一括インポート（whitelist_import.go）
+	ImportWhitelistCSV(ctx context.Context, r io.Reader, dryRun bool) (*models.WhitelistImportReport, error)
+
+	// スタッフ向け CSV / NDJSON エクスポート（whitelist_dump.go）
+	DumpWhitelist(ctx context.Context, w io.Writer, format string) error
+
+	// VRChat 表示名・アバターの再同期（whitelist_resync.go）
+	ResyncVRCProfile(ctx context.Context, u models.WhitelistUser) (*models.WhitelistNameChange, error)
+	ListNameHistory(ctx context.Context, discordID string) ([]models.WhitelistNameChange, error)
+
+	// 名前付きリスト（whitelist_lists.go）
+	CreateList(ctx context.Context, name, description string, selfService bool) (*models.WhitelistList, error)
+	GetLists(ctx context.Context) ([]models.WhitelistList, error)
+	GetListsByDiscord(ctx context.Context, discordID string) ([]models.WhitelistList, error)
+	AddToList(ctx context.Context, listName, discordID string) (added bool, err error)
+	RemoveFromList(ctx context.Context, listName, discordID string) (removed bool, err error)
+	ListWhitelistByList(ctx context.Context, listName string) ([]models.WhitelistUser, error)
+
+	// 有効期限
+	SetExpiry(ctx context.Context, discordID string, expiresAt *time.Time) error
+
+	RemoveExpired(ctx context.Context) ([]string, error)
+
+	// 管理者用メモ
+	SetNote(ctx context.Context, discordID, note string) error
+
+	// VRChat アカウントの本人確認（whitelist_verify.go）
+	VerifyDiscordVRC(ctx context.Context, discordID string) error
+	RemoveUnverified(ctx context.Context) ([]string, error)
+
+	// 論理削除の取り消しと物理削除
+	RestoreDiscord(ctx context.Context, discordID string) error
+	PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error)
+
+	// 登録禁止リスト（whitelist_deny.go）
+	DenyAccount(ctx context.Context, discordID, vrcUser, reason string) ([]models.WhitelistDeny, error)
+	ListDenied(ctx context.Context) ([]models.WhitelistDeny, error)
+	RemoveDeny(ctx context.Context, id uint64) error
+
+	// 受付期間と個別の許可（whitelist_window.go）
+	GetRegistrationWindow(ctx context.Context, listName string) (*models.RegistrationWindow, error)
+	SetRegistrationWindow(ctx context.Context, listName string, w models.RegistrationWindow) error
+	CheckRegistrationOpen(ctx context.Context, discordID, listName string) error
+	// 本人による登録の回数制限（whitelist_register_limit.go）
+	CheckRegisterRateLimit(ctx context.Context, discordID string) error
+	HasRegistrationOverride(ctx context.Context, discordID string) (bool, error)
+	GrantRegistrationOverride(ctx context.Context, discordID string) (granted bool, err error)
+	RevokeRegistrationOverride(ctx context.Context, discordID string) (revoked bool, err error)
+	ListRegistrationOverrides(ctx context.Context) ([]models.RegistrationOverride, error)
+	JoinList(ctx context.Context, listName, discordID string) (added bool, err error)
+
+	// 定員とキャンセル待ち（whitelist_waitlist.go）
+	GetCapacity(ctx context.Context) (*models.WhitelistCapacity, error)
+	GetWaitlistEntry(ctx context.Context, discordID string) (*models.WhitelistWaitlistEntry, error)
+	ListWaitlist(ctx context.Context) ([]models.WhitelistWaitlistEntry, error)
+	PromoteWaitlist(ctx context.Context) (promoted int, err error)
+
+	// 監査ログ（whitelist_audit.go）
+	ListAudit(ctx context.Context, f models.WhitelistAuditFilter) (*models.WhitelistAuditPage, error)
+}
+
+type whitelistService struct {
+	// 変更と監査ログを同じトランザクションで書く
+	tx           repository.Transactor
+	repo         repository.WhitelistRepository
+	listRepo     repository.WhitelistListRepository
+	auditRepo    repository.WhitelistAuditRepository
+	denyRepo     repository.WhitelistDenyRepository
+	windowRepo   repository.RegistrationWindowRepository
+	waitlistRepo repository.WhitelistWaitlistRepository
+	limitRepo    repository.RegisterLimitRepository
+	vrchat       VRChatClient
+	// nil ならロールは触らない
+	roleSyncer WhitelistRoleSyncer
+	// nil なら繰り上がっても連絡しない
+	waitlistNotifier WaitlistNotifier
+
+	verifyTimeout time.Duration // 本人確認コードの有効期間
+
+	// 本人による登録・変更の間隔と、1日に VRChat アカウントを変えられる回数（0 なら制限なし）
+	registerCooldown time.Duration
+	dailyChangeLimit int
+
+	// 定員（0 なら定員なし）。空き枠の判定から登録までをこのロックで1つずつ進める
+	capacity   int
+	capacityMu sync.Mutex
+}
+
+func NewWhitelistService(
+	tx repository.Transactor,
+	repo repository.WhitelistRepository,
+	listRepo repository.WhitelistListRepository,
+	auditRepo repository.WhitelistAuditRepository,
+	denyRepo repository.WhitelistDenyRepository,
+	windowRepo repository.RegistrationWindowRepository,
+	waitlistRepo repository.WhitelistWaitlistRepository,
+	limitRepo repository.RegisterLimitRepository,
+	vrchat VRChatClient,
+	roleSyncer WhitelistRoleSyncer,
+	waitlistNotifier WaitlistNotifier,
+) WhitelistService {
+	return &whitelistService{
+		tx:               tx,
+		repo:             repo,
+		listRepo:         listRepo,
+		auditRepo:        auditRepo,
+		denyRepo:         denyRepo,
+		windowRepo:       windowRepo,
+		waitlistRepo:     waitlistRepo,
+		limitRepo:        limitRepo,
+		vrchat:           vrchat,
+		roleSyncer:       roleSyncer,
+		verifyTimeout:    verifyTimeoutFromEnv(),
+		registerCooldown: registerCooldownFromEnv(),
+		dailyChangeLimit: dailyChangeLimitFromEnv(),
+		capacity:         capacityFromEnv(),
+		waitlistNotifier: waitlistNotifier,
+	}
+}
+
+// vrcUser は VRChat の表示名・ユーザーID（usr_...）・プロフィールURL のどれか。
+// ID / URL なら ID で直接引き、表示名なら完全一致で検索する。
+// 本人による登録なので、受付期間外（個別の許可も無し）なら ErrRegistrationClosed。
+// 続けて押し過ぎ・1日の変更回数の上限なら *RateLimitError（いつから試せるか入り）。
+// 回数制限は Discord の Interaction 経由の本人だけ（Actor.Admin と HTTP API は見ない）。
+// 新規で定員を超えていればキャンセル待ちに並べて *WaitlistedError を返す。
+func (s *whitelistService) RegisterDiscordVRC(
+	ctx context.Context,
+	discordID string,
+	vrcUser string,
+) (bool, error) {
+	return s.registerDiscordVRC(ctx, discordID, vrcUser, true)
+}
+
+// 管理者による登録。受付期間も定員も回数制限も見ず、本人確認も済んだ扱いにする（CSV インポートと同じ）。
+func (s *whitelistService) AdminRegisterDiscordVRC(ctx context.Context, discordID, vrcUser string) (bool, error) {
+	return s.registerDiscordVRC(ctx, discordID, vrcUser, false)
+}
+
+func (s *whitelistService) registerDiscordVRC(
+	ctx context.Context,
+	discordID string,
+	vrcUser string,
+	self bool,
+) (bool, error) {
+	// 空白削除
+	discordID = strings.TrimSpace(discordID)
+	vrcUser = strings.TrimSpace(vrcUser)
+	// どっちか空
+	if discordID == "" || vrcUser == "" {
+		return false, ErrInvalidArgument
+	}
+
+	// 受付期間と回数制限は VRChat API を叩く前に見る
+	limited := self && registerLimitApplies(ActorFrom(ctx))
+	if self {
+		if err := s.CheckRegistrationOpen(ctx, discordID, ""); err != nil {
+			return false, err
+		}
+	}
+	var attemptAt time.Time
+	if limited {
+		var err error
+		if attemptAt, err = s.reserveRegisterAttempt(ctx, discordID); err != nil {
+			return false, err
+		}
+	}
+
+	// 1. VRChat API でユーザーを特定
+	user, err := s.resolveVRCUser(ctx, vrcUser)
+	if limited {
+		s.releaseRegisterAttempt(ctx, discordID, attemptAt, err)
+	}
+	if err != nil {
+		// ErrNoExactMatch / ErrMultipleExactMatch / ErrVRCUserNotFound はそのまま上に返してハンドラー側で文言出す
+		return false, err
+	}
+
+	if !self {
+		return s.linkVRCUser(ctx, discordID, user, true)
+	}
+	return s.registerSelf(ctx, discordID, user, limited)
+}
+
+// 本人による登録の続き: 定員を見てから紐付ける（いっぱいならキャンセル待ちに並べる）。
+// limited なら、VRChat アカウントが変わったときに 1日の変更回数に数える。
+func (s *whitelistService) registerSelf(ctx context.Context, discordID string, user *VRChatUser, limited bool) (bool, error) {
+	s.capacityMu.Lock()
+	defer s.capacityMu.Unlock()
+
+	prevVRCUserID, err := s.currentVRCUserID(ctx, discordID)
+	if err != nil {
+		return false, err
+	}
+	full, err := s.waitlistFull(ctx, discordID)
+	if err != nil {
+		return false, err
+	}
+
+	var created bool
+	if full {
+		err = s.joinWaitlist(ctx, discordID, user)
+	} else {
+		created, err = s.linkVRCUser(ctx, discordID, user, false)
+	}
+	if limited && (err == nil || errors.Is(err, ErrWaitlisted)) && prevVRCUserID != user.ID {
+		s.recordRegisterChange(ctx, discordID)
+	}
+	return created, err
+}
+
+// usr_ の後ろは UUID。プロフィールURL は https://vrchat.com/home/user/usr_... の形。
+var vrcUserRefPattern = regexp.MustCompile(
+	`^(?:https?://(?:www\.)?vrchat\.com/home/user/)?(usr_[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})/?(?:[?#].*)?$`,
+)
+
+// 入力が VRChat のユーザーID かプロフィールURL なら ID を返す
+func parseVRCUserRef(v string) (string, bool) {
+	m := vrcUserRefPattern.FindStringSubmatch(v)
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+// 表示名・ID・URL のどれかから VRChat ユーザーを1人に決める
+func (s *whitelistService) resolveVRCUser(ctx context.Context, vrcUser string) (*VRChatUser, error) {
+	if id, ok := parseVRCUserRef(vrcUser); ok {
+		return s.vrchat.GetUserByID(ctx, id)
+	}
+	return s.vrchat.SearchExactUserByDisplayName(ctx, vrcUser)
+}
+
+// 2〜3: その VRC userID を discordID に紐付けてよいか確認し、今の紐付け（無ければ nil）を返す。
+// どちらかが登録禁止なら ErrDenied、他人が本人確認済みで使っていれば ErrAlreadyExists。一括インポートのドライランからも使う。
+func (s *whitelistService) checkLink(ctx context.Context, discordID, vrcUserID string) (*models.WhitelistUser, error) {
+	// 登録禁止リストに載っていないか先に確認
+	if err := s.checkDenied(ctx, discordID, vrcUserID); err != nil {
+		return nil, err
+	}
+
+	// 2. その VRC userID を他人が本人確認済みで使っていないか確認
+	// （本人確認待ちの紐付けはアカウントを押さえない。先に確認した人が取る）
+	existingByVRC, err := s.repo.GetByVRCUserID(ctx, vrcUserID)
+	// 使われていたらエラー
+	if err != nil {
+		return nil, err
+	}
+	// 他人のVRCアカウントは使っちゃダメ
+	if existingByVRC != nil && existingByVRC.DiscordUserID != discordID {
+		return nil, ErrAlreadyExists
+	}
+
+	// 3. Discordユーザの既存レコード確認
+	return s.repo.GetByDiscordID(ctx, discordID)
+}
+
+// 検索済みの VRChat ユーザーを discordID に紐付けて保存する。
+// verified なら本人確認済みとして保存する（管理者の追加・CSV インポート）。false なら本人の bio での確認待ち。
+// created = true → 新規, false → 新規ではなく更新
+func (s *whitelistService) linkVRCUser(ctx context.Context, discordID string, user *VRChatUser, verified bool) (bool, error) {
+	existingByDiscord, err := s.checkLink(ctx, discordID, user.ID)
+	if err != nil {
+		return false, err
+	}
+
+	u := &models.WhitelistUser{
+		DiscordUserID:  discordID,
+		VRCUserID:      user.ID,
+		VRCDisplayName: user.DisplayName,
+		VRCAvatarURL:   user.CurrentAvatarImageURL,
+	}
+	// 管理者のメモは登録し直しても残す（Upsert もメモは上書きしない）
+	if existingByDiscord != nil {
+		u.Note = existingByDiscord.Note
+	}
+
+	// 同じ VRChat アカウントのままなら本人確認の状態を引き継ぐ。
+	// 新規や別アカウントへの付け替えは、コードを発行して確認待ちにする（管理者が入れたものは確認済み）。
+	sameAccount := existingByDiscord != nil && existingByDiscord.VRCUserID == user.ID
+	switch {
+	case sameAccount && (existingByDiscord.VerifiedAt != nil || !verified):
+		u.VerifiedAt = existingByDiscord.VerifiedAt
+		u.VerifyCode = existingByDiscord.VerifyCode
+		u.VerifyUntil = existingByDiscord.VerifyUntil
+	case verified:
+		now := time.Now()
+		u.VerifiedAt = &now
+	default:
+		code, err := newVerifyCode()
+		if err != nil {
+			return false, err
+		}
+		until := time.Now().Add(s.verifyTimeout)
+		u.VerifyCode = code
+		u.VerifyUntil = &until
+	}
+
+	// created = true → 新規, false → 新規ではなく更新
+	created := (existingByDiscord == nil)
+	action := AuditActionUpdate
+	if created {
+		action = AuditActionRegister
+	}
+
+	// 4〜5. Upsert で (discordID, userID) を保存し、同じトランザクションで監査ログを残す
+	var (
+		after *models.WhitelistUser
+		lost  []models.WhitelistUser
+	)
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.Upsert(ctx, u); err != nil {
+			return err
+		}
+		// 保存後の値は DB から取り直す
+		var err error
+		if after, err = s.repo.GetByDiscordID(ctx, discordID); err != nil {
+			return err
+		}
+		if err := s.recordAudit(ctx, action, discordID, snapshotOf(existingByDiscord), snapshotOf(after)); err != nil {
+			return err
+		}
+		// 確認済みで入れたなら、同じアカウントで確認待ちの他の人は外す
+		if verified {
+			lost, err = s.claimVRCUser(ctx, after)
+		}
+		return err
+	})
+	if err != nil {
+		// 2 の確認後に他の人が同じ VRC アカウントで登録した場合
+		if errors.Is(err, repository.ErrDuplicate) {
+			return false, ErrAlreadyExists
+		}
+		return false, err
+	}
+
+	// 6. ロールの付け外し（別アカウントへの付け替えは本人確認待ちに戻るので外す）
+	s.syncRole(ctx, discordID, after.VerifiedAt != nil)
+
+	// 7. キャンセル待ちに並んでいたら列から外す（繰り上げ・管理者の追加など）
+	if _, err := s.leaveWaitlist(ctx, discordID); err != nil {
+		return created, err
+	}
+	if len(lost) > 0 {
+		s.promoteAfterRemoval(ctx)
+	}
+
+	return created, nil
+}
+
+func (s *whitelistService) GetDiscordVRC(ctx context.Context, discordID string) (*models.WhitelistUser, error) {
+	if discordID == "" {
+		return nil, ErrInvalidArgument
+	}
+	return s.repo.GetByDiscordID(ctx, discordID)
+}
+
+func (s *whitelistService) IsAllowedByDiscord(ctx context.Context, discordID string) (bool, error) {
+	if discordID == "" {
+		return false, ErrInvalidArgument
+	}
+	return s.repo.ExistsByDiscordID(ctx, discordID)
+}
+
+func (s *whitelistService) IsAllowedByVRCUserID(ctx context.Context, vrcUserID string) (bool, error) {
+	if vrcUserID == "" {
+		return false, ErrInvalidArgument
+	}
+	return s.repo.ExistsByVRCUserID(ctx, vrcUserID)
+}
+
+func (s *whitelistService) RemoveDiscord(ctx context.Context, discordID string) error {
+	_, err := s.removeDiscord(ctx, discordID, AuditActionRemove)
+	return err
+}
+
+// Discord サーバーを抜けた・BAN された人の紐付けを外す。論理削除なので restore で戻せる。
+// reason（AuditActionLeftGuild / AuditActionBanned）が監査ログの action になる。紐付けが無ければ false。
+func (s *whitelistService) RemoveDiscordForReason(ctx context.Context, discordID, reason string) (bool, error) {
+	switch reason {
+	case AuditActionLeftGuild, AuditActionBanned:
+	default:
+		return false, ErrInvalidArgument
+	}
+	return s.removeDiscord(ctx, discordID, reason)
+}
+
+func (s *whitelistService) removeDiscord(ctx context.Context, discordID, action string) (bool, error) {
+	if discordID == "" {
+		return false, ErrInvalidArgument
+	}
+
+	var removed, left bool
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		removed, left, err = s.unlinkDiscord(ctx, discordID, action)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	if !removed {
+		return left, nil
+	}
+	s.syncRole(ctx, discordID, false)
+
+	// 空いた枠にキャンセル待ちの先頭を繰り上げる
+	s.promoteAfterRemoval(ctx)
+	return true, nil
+}
+
+// 紐付けを外してキャンセル待ちからも抜き、監査ログを残す。トランザクションの中で呼ぶ。
+// ロールの付け外しと繰り上げはコミットのあとに呼び出し側でやる。
+func (s *whitelistService) unlinkDiscord(ctx context.Context, discordID, action string) (removed, left bool, err error) {
+	// 外した行を監査ログに残す。退出と BAN のイベントが同時に来ても、実際に外した側だけが記録する。
+	before, err := s.repo.RemoveByDiscordID(ctx, discordID)
+	if err != nil {
+		return false, false, err
+	}
+	// キャンセル待ちに並んでいるだけの人も外す
+	if left, err = s.leaveWaitlist(ctx, discordID); err != nil {
+		return false, false, err
+	}
+	if before == nil {
+		return false, left, nil
+	}
+	if err := s.recordAudit(ctx, action, discordID, snapshotOf(before), nil); err != nil {
+		return false, false, err
+	}
+	return true, left, nil
+}
+
+// ワールド向けエクスポート用に全件を登録順で返す
+func (s *whitelistService) ListWhitelist(ctx context.Context) ([]models.WhitelistUser, error) {
+	return s.repo.List(ctx)
+}
+
+// RegisterDiscordVRC / RemoveDiscord / リストの所属変更のたびに進む変更カウンタ
+func (s *whitelistService) GetWhitelistVersion(ctx context.Context) (*models.WhitelistVersion, error) {
+	return s.repo.GetVersion(ctx)
+}
+
+// 有効期限を設定する。nil なら無期限。過去の時刻は受け付けない。
+// 紐付けが無ければ ErrNotRegistered。
+func (s *whitelistService) SetExpiry(ctx context.Context, discordID string, expiresAt *time.Time) error {
+	discordID = strings.TrimSpace(discordID)
+	if discordID == "" {
+		return ErrInvalidArgument
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return ErrInvalidArgument
+	}
+
+	before, err := s.repo.GetByDiscordID(ctx, discordID)
+	if err != nil {
+		return err
+	}
+	if before == nil {
+		return ErrNotRegistered
+	}
+
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		ok, err := s.repo.SetExpiresAt(ctx, discordID, expiresAt)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrNotRegistered
+		}
+
+		after := *before
+		after.ExpiresAt = expiresAt
+		return s.recordAudit(ctx, AuditActionSetExpiry, discordID, snapshotOf(before), snapshotOf(&after))
+	})
+}
+
+// 管理者用メモを書き換える。空文字で消す。長すぎれば ErrInvalidArgument、紐付けが無ければ ErrNotRegistered。
+func (s *whitelistService) SetNote(ctx context.Context, discordID, note string) error {
+	discordID = strings.TrimSpace(discordID)
+	note = strings.TrimSpace(note)
+	if discordID == "" || utf8.RuneCountInString(note) > maxNoteLength {
+		return ErrInvalidArgument
+	}
+
+	before, err := s.repo.GetByDiscordID(ctx, discordID)
+	if err != nil {
+		return err
+	}
+	if before == nil {
+		return ErrNotRegistered
+	}
+
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		ok, err := s.repo.SetNote(ctx, discordID, note)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrNotRegistered
+		}
+
+		after := *before
+		after.Note = note
+		return s.recordAudit(ctx, AuditActionSetNote, discordID, snapshotOf(before), snapshotOf(&after))
+	})
+}
+
+// 期限切れの紐付けを外し、外れた Discord ID を返す。バックグラウンドのスイーパーから呼ばれる。
+func (s *whitelistService) RemoveExpired(ctx context.Context) ([]string, error) {
+	var removed []models.WhitelistUser
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		if removed, err = s.repo.RemoveExpired(ctx, time.Now()); err != nil {
+			return err
+		}
+		for i := range removed {
+			if err := s.recordAudit(ctx, AuditActionExpire, removed[i].DiscordUserID, snapshotOf(&removed[i]), nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(removed))
+	for i := range removed {
+		ids = append(ids, removed[i].DiscordUserID)
+		s.syncRole(ctx, removed[i].DiscordUserID, false)
+	}
+	if len(removed) > 0 {
+		s.promoteAfterRemoval(ctx)
+	}
+	return ids, nil
+}
+
+// 論理削除した紐付けを元に戻す。リスト所属もそのまま戻る。
+// 削除済みの紐付けが無ければ ErrNotFound、確認済みの紐付けでその VRChat アカウントを別の人が確認済みで使っていれば ErrAlreadyExists。
+func (s *whitelistService) RestoreDiscord(ctx context.Context, discordID string) error {
+	discordID = strings.TrimSpace(discordID)
+	if discordID == "" {
+		return ErrInvalidArgument
+	}
+
+	if err := s.checkDenied(ctx, discordID, ""); err != nil {
+		return err
+	}
+
+	var (
+		restored *models.WhitelistUser
+		lost     []models.WhitelistUser
+	)
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		if restored, err = s.repo.Restore(ctx, discordID); err != nil {
+			return err
+		}
+		if restored == nil {
+			return ErrNotFound
+		}
+		if err := s.recordAudit(ctx, AuditActionRestore, discordID, nil, snapshotOf(restored)); err != nil {
+			return err
+		}
+		// 確認済みの紐付けを戻したなら、その間に同じアカウントで確認待ちになった人は外す
+		lost, err = s.claimVRCUser(ctx, restored)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return ErrAlreadyExists
+		}
+		return err
+	}
+
+	// 戻した VRChat アカウントが登録禁止なら外し直す（削除済みの行は引けないので、戻してから確認する）
+	if err := s.checkDenied(ctx, "", restored.VRCUserID); err != nil {
+		if errors.Is(err, ErrDenied) {
+			if _, rerr := s.removeDiscord(ctx, discordID, AuditActionDenied); rerr != nil {
+				return rerr
+			}
+		}
+		return err
+	}
+	s.syncRole(ctx, discordID, restored.VerifiedAt != nil)
+	if len(lost) > 0 {
+		s.promoteAfterRemoval(ctx)
+	}
+	return nil
+}
+
+// deletedBefore より前に論理削除された紐付けを物理削除し、消した件数を返す。
+// もう戻せなくなるだけで見た目は変わらないので監査ログには残さない。
+func (s *whitelistService) PurgeDeleted(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	return s.repo.PurgeDeleted(ctx, deletedBefore)
+}
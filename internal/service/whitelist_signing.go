@@ -0,0 +1,59 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 署名付きエクスポートに使う鍵と生成時刻。
+// Secret はワールドトークン発行時に返した文字列をそのまま（hex のまま）鍵として使う。
+type ExportSignature struct {
+	Secret    string
+	Timestamp time.Time
+}
+
+func NewExportSignature(secret string) *ExportSignature {
+	return &ExportSignature{Secret: secret, Timestamp: time.Now()}
+}
+
+// HMAC-SHA256(secret, payload)
+func (sig *ExportSignature) sum(payload []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(sig.Secret))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// 1行に1件のテキストを作る。
+// sig が nil なら行を並べるだけ。sig があれば次の形にする:
+//
+//	#version=<バージョン>
+//	#timestamp=<生成時刻 unix秒>
+//	<行>...
+//	#signature=<HMAC-SHA256 の hex>
+//
+// 署名は最終行（#signature=）より前のバイト列すべてに対して計算する。
+func EncodeWhitelistText(lines []string, version int64, sig *ExportSignature) []byte {
+	var b strings.Builder
+	if sig != nil {
+		b.WriteString("#version=" + strconv.FormatInt(version, 10) + "\n")
+		b.WriteString("#timestamp=" + strconv.FormatInt(sig.Timestamp.Unix(), 10) + "\n")
+	}
+
+	// 改行が混ざると行がずれるので潰しておく
+	esc := strings.NewReplacer("\r", " ", "\n", " ")
+	for _, l := range lines {
+		b.WriteString(esc.Replace(l))
+		b.WriteString("\n")
+	}
+
+	if sig == nil {
+		return []byte(b.String())
+	}
+
+	body := b.String()
+	return []byte(body + "#signature=" + hex.EncodeToString(sig.sum([]byte(body))) + "\n")
+}
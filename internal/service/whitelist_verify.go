@@ -0,0 +1,163 @@
+package service
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository"
+	"context"
+	"crypto/rand"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	ErrVerifyCodeNotFound = errors.New("verify code not found in vrchat bio")
+	ErrVerifyExpired      = errors.New("verify code expired")
+)
+
+// 本人確認コードの有効期間のデフォルト
+const defaultVerifyTimeout = 24 * time.Hour
+
+// 紛らわしい文字（0/O, 1/I/L）を抜いた英数字
+const verifyCodeAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+// WHITELIST_VERIFY_TIMEOUT（例: 30m, 24h）で本人確認の期限を変えられる
+func verifyTimeoutFromEnv() time.Duration {
+	v := os.Getenv("WHITELIST_VERIFY_TIMEOUT")
+	if v == "" {
+		return defaultVerifyTimeout
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Printf("invalid WHITELIST_VERIFY_TIMEOUT=%q, using %s", v, defaultVerifyTimeout)
+		return defaultVerifyTimeout
+	}
+	return d
+}
+
+// bio に書いてもらうコード。例: YR-7KQ2MX
+func newVerifyCode() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i := range b {
+		b[i] = verifyCodeAlphabet[int(b[i])%len(verifyCodeAlphabet)]
+	}
+	return "YR-" + string(b), nil
+}
+
+// VRChat のプロフィール（bio）に発行したコードが書かれているか確認し、書かれていれば本人確認済みにする。
+// 紐付けが無ければ ErrNotRegistered、確認済みなら何もしない。
+// コードが見つからなければ ErrVerifyCodeNotFound、期限切れなら ErrVerifyExpired。
+// 同じ VRChat アカウントを他の人が先に確認済みにしていれば ErrAlreadyExists。
+// 確認できたら、同じ VRChat アカウントで確認待ちになっている他の人は外れる（先に確認した人がアカウントを取る）。
+func (s *whitelistService) VerifyDiscordVRC(ctx context.Context, discordID string) error {
+	discordID = strings.TrimSpace(discordID)
+	if discordID == "" {
+		return ErrInvalidArgument
+	}
+
+	link, err := s.repo.GetByDiscordID(ctx, discordID)
+	if err != nil {
+		return err
+	}
+	if link == nil {
+		return ErrNotRegistered
+	}
+	if link.VerifiedAt != nil {
+		return nil
+	}
+	if link.VerifyUntil != nil && !link.VerifyUntil.After(time.Now()) {
+		return ErrVerifyExpired
+	}
+
+	profile, err := s.vrchat.GetUserByID(ctx, link.VRCUserID)
+	if err != nil {
+		return err
+	}
+	if link.VerifyCode == "" || !strings.Contains(profile.Bio, link.VerifyCode) {
+		return ErrVerifyCodeNotFound
+	}
+
+	var (
+		verified *models.WhitelistUser
+		lost     []models.WhitelistUser
+	)
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		// 同時に押された等で既に確認済みになっていれば nil
+		if verified, err = s.repo.MarkVerified(ctx, discordID); err != nil || verified == nil {
+			return err
+		}
+		if err := s.recordAudit(ctx, AuditActionVerify, discordID, snapshotOf(link), snapshotOf(verified)); err != nil {
+			return err
+		}
+		lost, err = s.claimVRCUser(ctx, verified)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return ErrAlreadyExists
+		}
+		return err
+	}
+	if verified == nil {
+		return nil
+	}
+	s.syncRole(ctx, discordID, true)
+	if len(lost) > 0 {
+		s.promoteAfterRemoval(ctx)
+	}
+	return nil
+}
+
+// link が本人確認済みなら、同じ VRChat アカウントで確認待ちになっている他の人を外して返す。
+// 本人確認待ちの紐付けはアカウントを押さえないので、先に確認した人（か管理者が入れた人）が取る。
+// s.tx.WithinTx の中で呼ぶこと。
+func (s *whitelistService) claimVRCUser(ctx context.Context, link *models.WhitelistUser) ([]models.WhitelistUser, error) {
+	if link == nil || link.VerifiedAt == nil {
+		return nil, nil
+	}
+	removed, err := s.repo.RemovePendingByVRCUserID(ctx, link.VRCUserID, link.DiscordUserID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range removed {
+		if err := s.recordAudit(ctx, AuditActionVerifyLost, removed[i].DiscordUserID, snapshotOf(&removed[i]), nil); err != nil {
+			return nil, err
+		}
+	}
+	return removed, nil
+}
+
+// 本人確認の期限が過ぎた紐付けを外し、外れた Discord ID を返す。バックグラウンドのスイーパーから呼ばれる。
+func (s *whitelistService) RemoveUnverified(ctx context.Context) ([]string, error) {
+	var removed []models.WhitelistUser
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		if removed, err = s.repo.RemoveUnverified(ctx, time.Now()); err != nil {
+			return err
+		}
+		for i := range removed {
+			if err := s.recordAudit(ctx, AuditActionUnverified, removed[i].DiscordUserID, snapshotOf(&removed[i]), nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(removed))
+	for i := range removed {
+		ids = append(ids, removed[i].DiscordUserID)
+	}
+	if len(removed) > 0 {
+		s.promoteAfterRemoval(ctx)
+	}
+	return ids, nil
+}
@@ -0,0 +1,240 @@
+package service
+
+import (
+	"backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+)
+
+// 定員を超えたのでキャンセル待ちに並んだ。順番は *WaitlistedError で返す。
+var ErrWaitlisted = errors.New("whitelist is full; added to the waitlist")
+
+type WaitlistedError struct {
+	Position int
+}
+
+func (e *WaitlistedError) Error() string {
+	return fmt.Sprintf("%s (position %d)", ErrWaitlisted.Error(), e.Position)
+}
+
+// errors.Is(err, ErrWaitlisted) で判定できるように
+func (e *WaitlistedError) Is(target error) bool {
+	return target == ErrWaitlisted
+}
+
+// キャンセル待ちから繰り上がった人への連絡。discord.WaitlistNotifier が満たす。
+type WaitlistNotifier interface {
+	NotifyPromoted(ctx context.Context, u *models.WhitelistUser) error
+}
+
+// WHITELIST_CAPACITY でホワイトリストの定員を決める。0 か未設定なら定員なし。
+func capacityFromEnv() int {
+	v := os.Getenv("WHITELIST_CAPACITY")
+	if v == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		log.Printf("invalid WHITELIST_CAPACITY=%q, capacity disabled", v)
+		return 0
+	}
+	return n
+}
+
+// 監査ログに残すキャンセル待ちのスナップショット
+func waitlistSnapshot(e *models.WhitelistWaitlistEntry) json.RawMessage {
+	if e == nil {
+		return nil
+	}
+	b, _ := json.Marshal(e)
+	return b
+}
+
+// discordID の新規登録を定員で止めるか。capacityMu を持った状態で呼ぶ。
+// 今の紐付けの変更は枠が増えないので止めない。空きがあっても、先に並んでいる人がいれば追い越させない。
+func (s *whitelistService) waitlistFull(ctx context.Context, discordID string) (bool, error) {
+	if s.capacity <= 0 {
+		return false, nil
+	}
+
+	existing, err := s.repo.GetByDiscordID(ctx, discordID)
+	if err != nil {
+		return false, err
+	}
+	if existing != nil {
+		return false, nil
+	}
+
+	n, err := s.repo.CountActive(ctx)
+	if err != nil {
+		return false, err
+	}
+	if n >= s.capacity {
+		return true, nil
+	}
+
+	head, err := s.waitlistRepo.Next(ctx)
+	if err != nil {
+		return false, err
+	}
+	return head != nil && head.DiscordUserID != discordID, nil
+}
+
+// キャンセル待ちに並ぶ（既に並んでいれば VRChat アカウントだけ差し替える）。
+// 登録禁止や他人の VRChat アカウントの確認は通常の登録と同じ。成功すれば *WaitlistedError を返す。
+// 同じ VRChat アカウントで他の人が並んでいても止めない（繰り上げのときに先に本人確認した人が勝つ）。
+func (s *whitelistService) joinWaitlist(ctx context.Context, discordID string, user *VRChatUser) error {
+	if _, err := s.checkLink(ctx, discordID, user.ID); err != nil {
+		return err
+	}
+
+	before, err := s.waitlistRepo.GetByDiscordID(ctx, discordID)
+	if err != nil {
+		return err
+	}
+	e := &models.WhitelistWaitlistEntry{
+		DiscordUserID:  discordID,
+		VRCUserID:      user.ID,
+		VRCDisplayName: user.DisplayName,
+		VRCAvatarURL:   user.CurrentAvatarImageURL,
+	}
+	var after *models.WhitelistWaitlistEntry
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.waitlistRepo.Upsert(ctx, e); err != nil {
+			return err
+		}
+		var err error
+		if after, err = s.waitlistRepo.GetByDiscordID(ctx, discordID); err != nil {
+			return err
+		}
+		if after == nil {
+			after = e
+		}
+		return s.recordAudit(ctx, AuditActionWaitlistAdd, discordID, waitlistSnapshot(before), waitlistSnapshot(after))
+	})
+	if err != nil {
+		return err
+	}
+
+	return &WaitlistedError{Position: after.Position}
+}
+
+// キャンセル待ちから外す。並んでいなければ false。
+func (s *whitelistService) leaveWaitlist(ctx context.Context, discordID string) (bool, error) {
+	left := false
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		e, err := s.waitlistRepo.Delete(ctx, discordID)
+		if err != nil || e == nil {
+			return err
+		}
+		left = true
+		return s.recordAudit(ctx, AuditActionWaitlistRemove, discordID, waitlistSnapshot(e), nil)
+	})
+	return left, err
+}
+
+// 空いている枠の分だけ、キャンセル待ちの先頭から登録する。繰り上がった人数を返す。
+// 繰り上がった人は本人確認待ちになり、DM で確認コードを知らせる。
+// 並んでいる間に登録禁止になった・VRChat アカウントを他の人が本人確認まで済ませた人は列から外して次へ進む。
+func (s *whitelistService) PromoteWaitlist(ctx context.Context) (int, error) {
+	s.capacityMu.Lock()
+	defer s.capacityMu.Unlock()
+
+	// 繰り上げは自動なので、監査ログの actor はきっかけを作った人ではなく system
+	ctx = WithActor(ctx, Actor{ID: "system", Source: SourceSystem, RequestID: ActorFrom(ctx).RequestID})
+
+	promoted := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return promoted, err
+		}
+		if s.capacity > 0 {
+			n, err := s.repo.CountActive(ctx)
+			if err != nil {
+				return promoted, err
+			}
+			if n >= s.capacity {
+				return promoted, nil
+			}
+		}
+
+		head, err := s.waitlistRepo.Next(ctx)
+		if err != nil || head == nil {
+			return promoted, err
+		}
+
+		user := &VRChatUser{
+			ID:                    head.VRCUserID,
+			DisplayName:           head.VRCDisplayName,
+			CurrentAvatarImageURL: head.VRCAvatarURL,
+		}
+		// 本人が並んだものなので、繰り上げても本人確認は要る
+		_, err = s.linkVRCUser(ctx, head.DiscordUserID, user, false)
+		if errors.Is(err, ErrDenied) || errors.Is(err, ErrAlreadyExists) {
+			if _, err := s.leaveWaitlist(ctx, head.DiscordUserID); err != nil {
+				return promoted, err
+			}
+			continue
+		}
+		if err != nil {
+			return promoted, err
+		}
+
+		promoted++
+		s.notifyPromoted(ctx, head.DiscordUserID)
+	}
+}
+
+// 枠が空いたあとの繰り上げ。失敗しても空けた側の処理は取り消さない（次に枠が空いたときにまた試す）。
+func (s *whitelistService) promoteAfterRemoval(ctx context.Context) {
+	if _, err := s.PromoteWaitlist(ctx); err != nil {
+		log.Printf("whitelist waitlist promotion failed: %+v", err)
+	}
+}
+
+func (s *whitelistService) notifyPromoted(ctx context.Context, discordID string) {
+	if s.waitlistNotifier == nil {
+		return
+	}
+	u, err := s.repo.GetByDiscordID(ctx, discordID)
+	if err != nil || u == nil {
+		log.Printf("whitelist waitlist: reload after promotion failed (discord_user_id=%s): %+v", discordID, err)
+		return
+	}
+	if err := s.waitlistNotifier.NotifyPromoted(ctx, u); err != nil {
+		log.Printf("whitelist waitlist: notify failed (discord_user_id=%s): %+v", discordID, err)
+	}
+}
+
+// キャンセル待ちの順番。並んでいなければ nil。
+func (s *whitelistService) GetWaitlistEntry(ctx context.Context, discordID string) (*models.WhitelistWaitlistEntry, error) {
+	if discordID == "" {
+		return nil, ErrInvalidArgument
+	}
+	return s.waitlistRepo.GetByDiscordID(ctx, discordID)
+}
+
+func (s *whitelistService) ListWaitlist(ctx context.Context) ([]models.WhitelistWaitlistEntry, error) {
+	return s.waitlistRepo.List(ctx)
+}
+
+func (s *whitelistService) GetCapacity(ctx context.Context) (*models.WhitelistCapacity, error) {
+	registered, err := s.repo.CountActive(ctx)
+	if err != nil {
+		return nil, err
+	}
+	waiting, err := s.waitlistRepo.Count(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &models.WhitelistCapacity{
+		Capacity:   s.capacity,
+		Registered: registered,
+		Waiting:    waiting,
+	}, nil
+}
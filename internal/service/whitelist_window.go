@@ -0,0 +1,145 @@
+package service
+
+import (
+	"backend/internal/models"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// 受付期間外の本人による登録・リスト参加
+var ErrRegistrationClosed = errors.New("registration is closed")
+
+// 受付期間。listName が空ならホワイトリスト全体（本人による登録）、それ以外はそのリストへの参加。
+func (s *whitelistService) GetRegistrationWindow(ctx context.Context, listName string) (*models.RegistrationWindow, error) {
+	if strings.TrimSpace(listName) == "" {
+		return s.windowRepo.Get(ctx)
+	}
+	list, err := s.getList(ctx, listName)
+	if err != nil {
+		return nil, err
+	}
+	return &list.RegistrationWindow, nil
+}
+
+// 受付期間を置き換える。両方 nil なら制限なしに戻す。開始が終了より後なら ErrInvalidArgument。
+// 変更前後を監査ログに残す（特定の人への操作ではないので Discord ID は空）。
+func (s *whitelistService) SetRegistrationWindow(ctx context.Context, listName string, w models.RegistrationWindow) error {
+	if w.OpensAt != nil && w.ClosesAt != nil && !w.OpensAt.Before(*w.ClosesAt) {
+		return ErrInvalidArgument
+	}
+	listName = strings.TrimSpace(listName)
+
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		before, err := s.GetRegistrationWindow(ctx, listName)
+		if err != nil {
+			return err
+		}
+		if listName == "" {
+			err = s.windowRepo.Set(ctx, w)
+		} else {
+			var list *models.WhitelistList
+			if list, err = s.getList(ctx, listName); err != nil {
+				return err
+			}
+			err = s.listRepo.SetRegistrationWindow(ctx, list.ID, w)
+		}
+		if err != nil {
+			return err
+		}
+		return s.recordAudit(ctx, AuditActionWindowSet, "", windowSnapshot(listName, *before), windowSnapshot(listName, w))
+	})
+}
+
+// 受付期間内か、管理者に個別に許可されていれば nil。そうでなければ ErrRegistrationClosed。
+func (s *whitelistService) CheckRegistrationOpen(ctx context.Context, discordID, listName string) error {
+	w, err := s.GetRegistrationWindow(ctx, listName)
+	if err != nil {
+		return err
+	}
+	if w.IsOpen(time.Now()) {
+		return nil
+	}
+
+	ok, err := s.windowRepo.HasOverride(ctx, discordID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrRegistrationClosed
+	}
+	return nil
+}
+
+func (s *whitelistService) HasRegistrationOverride(ctx context.Context, discordID string) (bool, error) {
+	return s.windowRepo.HasOverride(ctx, discordID)
+}
+
+// 受付期間の外でも登録・リスト参加できるようにする。既に許可済みなら false。
+func (s *whitelistService) GrantRegistrationOverride(ctx context.Context, discordID string) (bool, error) {
+	discordID = strings.TrimSpace(discordID)
+	if discordID == "" {
+		return false, ErrInvalidArgument
+	}
+
+	o := &models.RegistrationOverride{
+		DiscordUserID: discordID,
+		GrantedBy:     ActorFrom(ctx).ID,
+	}
+	added := false
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		if added, err = s.windowRepo.AddOverride(ctx, o); err != nil || !added {
+			return err
+		}
+		return s.recordAudit(ctx, AuditActionWindowOverrideAdd, discordID, nil, overrideSnapshot(o))
+	})
+	return added, err
+}
+
+// 個別の許可を取り消す。許可されていなければ false。登録済みの紐付けはそのまま。
+func (s *whitelistService) RevokeRegistrationOverride(ctx context.Context, discordID string) (bool, error) {
+	discordID = strings.TrimSpace(discordID)
+	if discordID == "" {
+		return false, ErrInvalidArgument
+	}
+
+	removed := false
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		if removed, err = s.windowRepo.RemoveOverride(ctx, discordID); err != nil || !removed {
+			return err
+		}
+		return s.recordAudit(ctx, AuditActionWindowOverrideRemove, discordID, nil, nil)
+	})
+	return removed, err
+}
+
+func (s *whitelistService) ListRegistrationOverrides(ctx context.Context) ([]models.RegistrationOverride, error) {
+	return s.windowRepo.ListOverrides(ctx)
+}
+
+// 本人による self_service リストへの参加。リストの受付期間を見てから AddToList する。
+func (s *whitelistService) JoinList(ctx context.Context, listName, discordID string) (bool, error) {
+	if err := s.CheckRegistrationOpen(ctx, discordID, listName); err != nil {
+		return false, err
+	}
+	return s.AddToList(ctx, listName, discordID)
+}
+
+// 監査ログに残す個別許可のスナップショット
+func overrideSnapshot(o *models.RegistrationOverride) json.RawMessage {
+	b, _ := json.Marshal(o)
+	return b
+}
+
+// 監査ログに残す受付期間のスナップショット。list が空ならホワイトリスト全体。
+func windowSnapshot(listName string, w models.RegistrationWindow) json.RawMessage {
+	b, _ := json.Marshal(struct {
+		List string `json:"list,omitempty"`
+		models.RegistrationWindow
+	}{listName, w})
+	return b
+}
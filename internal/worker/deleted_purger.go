@@ -0,0 +1,57 @@
+package worker
+
+import (
+	"backend/internal/service"
+	"context"
+	"log"
+	"time"
+)
+
+// 論理削除から一定期間たったホワイトリスト登録を物理削除するワーカー
+type DeletedPurger struct {
+	svc       service.WhitelistService
+	interval  time.Duration
+	retention time.Duration
+}
+
+// WHITELIST_DELETED_RETENTION（デフォルト720h = 30日）を過ぎた削除済みの紐付けを、
+// WHITELIST_PURGE_INTERVAL（デフォルト1h）ごとに消す。
+func NewDeletedPurger(svc service.WhitelistService) *DeletedPurger {
+	return &DeletedPurger{
+		svc:       svc,
+		interval:  durationFromEnv("WHITELIST_PURGE_INTERVAL", time.Hour),
+		retention: durationFromEnv("WHITELIST_DELETED_RETENTION", 30*24*time.Hour),
+	}
+}
+
+// ctx が終わるまで interval ごとに物理削除する。main.go からゴルーチンで呼ぶ。
+func (w *DeletedPurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	// 起動直後にも1回まわしておく
+	w.purge(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.purge(ctx)
+		}
+	}
+}
+
+func (w *DeletedPurger) purge(ctx context.Context) {
+	n, err := w.svc.PurgeDeleted(ctx, time.Now().Add(-w.retention))
+	if err != nil {
+		// シャットダウン中のキャンセルはエラー扱いしない
+		if ctx.Err() == nil {
+			log.Printf("deleted purge failed: %+v", err)
+		}
+		return
+	}
+	if n > 0 {
+		log.Printf("deleted purge: purged %d whitelist entries deleted more than %s ago", n, w.retention)
+	}
+}
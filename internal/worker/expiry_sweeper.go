@@ -0,0 +1,80 @@
+package worker
+
+import (
+	"backend/internal/service"
+	"context"
+	"log"
+	"os"
+	"time"
+)
+
+// 有効期限切れ・本人確認の期限切れのホワイトリスト登録を定期的に外すワーカー
+type ExpirySweeper struct {
+	svc      service.WhitelistService
+	interval time.Duration
+}
+
+// WHITELIST_SWEEP_INTERVAL（例: 30s, 5m）で間隔を変えられる。デフォルト1分。
+func NewExpirySweeper(svc service.WhitelistService) *ExpirySweeper {
+	return &ExpirySweeper{
+		svc:      svc,
+		interval: durationFromEnv("WHITELIST_SWEEP_INTERVAL", time.Minute),
+	}
+}
+
+// ctx が終わるまで interval ごとに掃除する。main.go からゴルーチンで呼ぶ。
+func (w *ExpirySweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	// 起動直後にも1回まわしておく
+	w.sweep(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.sweep(ctx)
+		}
+	}
+}
+
+// 環境変数から正の time.Duration を読む。未設定・不正ならデフォルト。
+func durationFromEnv(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Printf("invalid %s=%q, using %s", key, v, def)
+		return def
+	}
+	return d
+}
+
+func (w *ExpirySweeper) sweep(ctx context.Context) {
+	removed, err := w.svc.RemoveExpired(ctx)
+	if err != nil {
+		// シャットダウン中のキャンセルはエラー扱いしない
+		if ctx.Err() == nil {
+			log.Printf("expiry sweep failed: %+v", err)
+		}
+		return
+	}
+	if len(removed) > 0 {
+		log.Printf("expiry sweep: removed %d whitelist entries: %v", len(removed), removed)
+	}
+
+	unverified, err := w.svc.RemoveUnverified(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("unverified sweep failed: %+v", err)
+		}
+		return
+	}
+	if len(unverified) > 0 {
+		log.Printf("expiry sweep: removed %d unverified whitelist entries: %v", len(unverified), unverified)
+	}
+}
@@ -0,0 +1,125 @@
+package worker
+
+import (
+	"backend/internal/models"
+	"backend/internal/service"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// 管理者向けの通知先（Discord の管理チャンネルなど）
+type Notifier interface {
+	Notify(ctx context.Context, msg string) error
+}
+
+// Discord のメッセージ上限（2000文字）に収まるように切る長さ
+const maxNotifyLength = 1900
+
+// 登録済みの VRChat 表示名・アバターを定期的に取り直すワーカー
+type VRCResyncer struct {
+	svc      service.WhitelistService
+	notifier Notifier // nil なら通知しない
+	interval time.Duration
+	rate     time.Duration
+}
+
+// WHITELIST_RESYNC_INTERVAL（デフォルト24h）ごとに全件を取り直す。
+// VRChat API を叩き過ぎないよう、1件ごとに WHITELIST_RESYNC_RATE（デフォルト2s）空ける。
+func NewVRCResyncer(svc service.WhitelistService, notifier Notifier) *VRCResyncer {
+	return &VRCResyncer{
+		svc:      svc,
+		notifier: notifier,
+		interval: durationFromEnv("WHITELIST_RESYNC_INTERVAL", 24*time.Hour),
+		rate:     durationFromEnv("WHITELIST_RESYNC_RATE", 2*time.Second),
+	}
+}
+
+// ctx が終わるまで interval ごとに再同期する。main.go からゴルーチンで呼ぶ。
+func (w *VRCResyncer) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	// 起動直後にも1回まわしておく
+	w.resync(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.resync(ctx)
+		}
+	}
+}
+
+func (w *VRCResyncer) resync(ctx context.Context) {
+	users, err := w.svc.ListWhitelist(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("vrc resync: list failed: %+v", err)
+		}
+		return
+	}
+
+	limiter := time.NewTicker(w.rate)
+	defer limiter.Stop()
+
+	var (
+		changes  []models.WhitelistNameChange
+		notFound int
+		failed   int
+	)
+	for _, u := range users {
+		select {
+		case <-ctx.Done():
+			return
+		case <-limiter.C:
+		}
+
+		change, err := w.svc.ResyncVRCProfile(ctx, u)
+		switch {
+		case errors.Is(err, service.ErrVRCUserNotFound):
+			notFound++
+		case err != nil:
+			if ctx.Err() != nil {
+				return
+			}
+			failed++
+			log.Printf("vrc resync: discord=%s vrc=%s failed: %+v", u.DiscordUserID, u.VRCUserID, err)
+		case change != nil:
+			changes = append(changes, *change)
+		}
+	}
+
+	log.Printf("vrc resync: checked=%d renamed=%d not_found=%d failed=%d", len(users), len(changes), notFound, failed)
+
+	if len(changes) == 0 || w.notifier == nil {
+		return
+	}
+	if err := w.notifier.Notify(ctx, formatRenameSummary(changes, notFound)); err != nil {
+		log.Printf("vrc resync: notify failed: %+v", err)
+	}
+}
+
+// 管理チャンネル向けのまとめ
+// 例: - <@123> 「旧い名前」→「新しい名前」
+func formatRenameSummary(changes []models.WhitelistNameChange, notFound int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "🔄 VRChat名の変更を %d 件検出した。", len(changes))
+	if notFound > 0 {
+		fmt.Fprintf(&b, "（VRChat 側で見つからなかった登録: %d 件）", notFound)
+	}
+	for i, c := range changes {
+		line := fmt.Sprintf("\n- <@%s> 「%s」→「%s」", c.DiscordUserID, c.OldDisplayName, c.NewDisplayName)
+		if b.Len()+len(line) > maxNotifyLength {
+			fmt.Fprintf(&b, "\n…ほか %d 件", len(changes)-i)
+			break
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
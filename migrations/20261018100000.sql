@@ -0,0 +1,8 @@
+-- Create "whitelist_version" table
+CREATE TABLE "public"."whitelist_version" (
+  "id" smallint NOT NULL DEFAULT 1,
+  "version" bigint NOT NULL DEFAULT 0,
+  "updated_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "whitelist_version_id_check" CHECK (id = 1)
+);
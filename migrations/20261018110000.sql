@@ -0,0 +1,14 @@
+-- Create "world_export_tokens" table
+CREATE TABLE "public"."world_export_tokens" (
+  "id" bigserial NOT NULL,
+  "world_name" character varying(128) NOT NULL,
+  "token_hash" character(64) NOT NULL,
+  "token_prefix" character varying(16) NOT NULL,
+  "secret" character varying(128) NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "last_used_at" timestamptz NULL,
+  "revoked_at" timestamptz NULL,
+  PRIMARY KEY ("id")
+);
+-- Create index "uq_world_export_token_hash" to table: "world_export_tokens"
+CREATE UNIQUE INDEX "uq_world_export_token_hash" ON "public"."world_export_tokens" ("token_hash");
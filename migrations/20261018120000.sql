@@ -0,0 +1,22 @@
+-- Create "whitelist_lists" table
+CREATE TABLE "public"."whitelist_lists" (
+  "id" bigserial NOT NULL,
+  "name" character varying(64) NOT NULL,
+  "description" character varying(255) NOT NULL DEFAULT '',
+  "self_service" boolean NOT NULL DEFAULT false,
+  "created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+-- Create index "uq_whitelist_list_name" to table: "whitelist_lists"
+CREATE UNIQUE INDEX "uq_whitelist_list_name" ON "public"."whitelist_lists" ("name");
+-- Create "whitelist_list_members" table
+CREATE TABLE "public"."whitelist_list_members" (
+  "list_id" bigint NOT NULL,
+  "whitelist_user_id" bigint NOT NULL,
+  "created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("list_id", "whitelist_user_id"),
+  CONSTRAINT "whitelist_list_members_list_id_fkey" FOREIGN KEY ("list_id") REFERENCES "public"."whitelist_lists" ("id") ON UPDATE NO ACTION ON DELETE CASCADE,
+  CONSTRAINT "whitelist_list_members_whitelist_user_id_fkey" FOREIGN KEY ("whitelist_user_id") REFERENCES "public"."whitelist_users" ("id") ON UPDATE NO ACTION ON DELETE CASCADE
+);
+-- Create index "idx_whitelist_list_members_user" to table: "whitelist_list_members"
+CREATE INDEX "idx_whitelist_list_members_user" ON "public"."whitelist_list_members" ("whitelist_user_id");
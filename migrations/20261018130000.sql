@@ -0,0 +1,4 @@
+-- Modify "whitelist_users" table
+ALTER TABLE "public"."whitelist_users" ADD COLUMN "expires_at" timestamptz NULL;
+-- Create index "idx_whitelist_users_expires_at" to table: "whitelist_users"
+CREATE INDEX "idx_whitelist_users_expires_at" ON "public"."whitelist_users" ("expires_at");
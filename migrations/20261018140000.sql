@@ -0,0 +1,15 @@
+-- Create "whitelist_audit" table
+CREATE TABLE "public"."whitelist_audit" (
+  "id" bigserial NOT NULL,
+  "action" character varying(32) NOT NULL,
+  "discord_user_id" character varying(64) NOT NULL,
+  "actor" character varying(128) NOT NULL DEFAULT '',
+  "source" character varying(32) NOT NULL,
+  "before_value" jsonb NULL,
+  "after_value" jsonb NULL,
+  "request_id" character varying(64) NOT NULL DEFAULT '',
+  "created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_whitelist_audit_discord_user" to table: "whitelist_audit"
+CREATE INDEX "idx_whitelist_audit_discord_user" ON "public"."whitelist_audit" ("discord_user_id", "id");
@@ -0,0 +1,8 @@
+-- Modify "whitelist_users" table
+ALTER TABLE "public"."whitelist_users" ADD COLUMN "deleted_at" timestamptz NULL;
+-- Drop index "uq_vrc_user" from table: "whitelist_users"
+DROP INDEX "public"."uq_vrc_user";
+-- Create index "uq_vrc_user" to table: "whitelist_users"
+CREATE UNIQUE INDEX "uq_vrc_user" ON "public"."whitelist_users" ("vrc_user_id") WHERE (deleted_at IS NULL);
+-- Create index "idx_whitelist_users_deleted_at" to table: "whitelist_users"
+CREATE INDEX "idx_whitelist_users_deleted_at" ON "public"."whitelist_users" ("deleted_at");
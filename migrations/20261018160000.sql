@@ -0,0 +1,12 @@
+-- Create "whitelist_name_history" table
+CREATE TABLE "public"."whitelist_name_history" (
+  "id" bigserial NOT NULL,
+  "discord_user_id" character varying(64) NOT NULL,
+  "vrc_user_id" character varying(64) NOT NULL,
+  "old_display_name" character varying(64) NOT NULL,
+  "new_display_name" character varying(64) NOT NULL,
+  "changed_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+-- Create index "idx_whitelist_name_history_discord_user" to table: "whitelist_name_history"
+CREATE INDEX "idx_whitelist_name_history_discord_user" ON "public"."whitelist_name_history" ("discord_user_id", "id");
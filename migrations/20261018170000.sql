@@ -0,0 +1,6 @@
+-- Modify "whitelist_users" table
+ALTER TABLE "public"."whitelist_users" ADD COLUMN "verified_at" timestamptz NULL, ADD COLUMN "verify_code" character varying(16) NOT NULL DEFAULT '', ADD COLUMN "verify_until" timestamptz NULL;
+-- Backfill: links made before verification existed are treated as verified
+UPDATE "public"."whitelist_users" SET "verified_at" = "created_at";
+-- Create index "idx_whitelist_users_verify_until" to table: "whitelist_users"
+CREATE INDEX "idx_whitelist_users_verify_until" ON "public"."whitelist_users" ("verify_until");
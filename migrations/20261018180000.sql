@@ -0,0 +1,15 @@
+-- Create "whitelist_denylist" table
+CREATE TABLE "public"."whitelist_denylist" (
+  "id" bigserial NOT NULL,
+  "discord_user_id" character varying(64) NULL,
+  "vrc_user_id" character varying(64) NULL,
+  "reason" character varying(255) NOT NULL DEFAULT '',
+  "added_by" character varying(128) NOT NULL DEFAULT '',
+  "created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "chk_denylist_target" CHECK ((discord_user_id IS NOT NULL) OR (vrc_user_id IS NOT NULL))
+);
+-- Create index "uq_denylist_discord_user" to table: "whitelist_denylist"
+CREATE UNIQUE INDEX "uq_denylist_discord_user" ON "public"."whitelist_denylist" ("discord_user_id");
+-- Create index "uq_denylist_vrc_user" to table: "whitelist_denylist"
+CREATE UNIQUE INDEX "uq_denylist_vrc_user" ON "public"."whitelist_denylist" ("vrc_user_id");
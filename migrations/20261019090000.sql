@@ -0,0 +1,18 @@
+-- Modify "whitelist_lists" table
+ALTER TABLE "public"."whitelist_lists" ADD COLUMN "registration_opens_at" timestamptz NULL, ADD COLUMN "registration_closes_at" timestamptz NULL;
+-- Create "whitelist_registration_window" table
+CREATE TABLE "public"."whitelist_registration_window" (
+  "id" smallint NOT NULL DEFAULT 1,
+  "opens_at" timestamptz NULL,
+  "closes_at" timestamptz NULL,
+  "updated_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id"),
+  CONSTRAINT "whitelist_registration_window_id_check" CHECK (id = 1)
+);
+-- Create "whitelist_registration_overrides" table
+CREATE TABLE "public"."whitelist_registration_overrides" (
+  "discord_user_id" character varying(64) NOT NULL,
+  "granted_by" character varying(128) NOT NULL DEFAULT '',
+  "created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("discord_user_id")
+);
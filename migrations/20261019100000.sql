@@ -0,0 +1,15 @@
+-- Create "whitelist_waitlist" table
+CREATE TABLE "public"."whitelist_waitlist" (
+  "id" bigserial NOT NULL,
+  "discord_user_id" character varying(64) NOT NULL,
+  "vrc_user_id" character varying(64) NOT NULL,
+  "vrc_display_name" character varying(64) NOT NULL,
+  "vrc_avatar_url" character varying(512) NULL,
+  "created_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  "updated_at" timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY ("id")
+);
+-- Create index "uq_waitlist_discord_user" to table: "whitelist_waitlist"
+CREATE UNIQUE INDEX "uq_waitlist_discord_user" ON "public"."whitelist_waitlist" ("discord_user_id");
+-- Create index "uq_waitlist_vrc_user" to table: "whitelist_waitlist"
+CREATE UNIQUE INDEX "uq_waitlist_vrc_user" ON "public"."whitelist_waitlist" ("vrc_user_id");
@@ -0,0 +1,8 @@
+-- Create "whitelist_register_limits" table
+CREATE TABLE "public"."whitelist_register_limits" (
+  "discord_user_id" character varying(64) NOT NULL,
+  "last_attempt_at" timestamptz NOT NULL,
+  "changes_since" timestamptz NOT NULL,
+  "change_count" integer NOT NULL DEFAULT 0,
+  PRIMARY KEY ("discord_user_id")
+);
@@ -0,0 +1,6 @@
+-- Drop index "uq_vrc_user" from table: "whitelist_users"
+DROP INDEX "public"."uq_vrc_user";
+-- Create index "uq_vrc_user" to table: "whitelist_users"
+CREATE UNIQUE INDEX "uq_vrc_user" ON "public"."whitelist_users" ("vrc_user_id") WHERE ((deleted_at IS NULL) AND (verified_at IS NOT NULL));
+-- Create index "idx_whitelist_users_vrc_user_id" to table: "whitelist_users"
+CREATE INDEX "idx_whitelist_users_vrc_user_id" ON "public"."whitelist_users" ("vrc_user_id") WHERE (deleted_at IS NULL);
@@ -0,0 +1,2 @@
+-- Drop index "uq_waitlist_vrc_user" from table: "whitelist_waitlist"
+DROP INDEX "public"."uq_waitlist_vrc_user";
@@ -10,9 +10,156 @@ CREATE TABLE whitelist_users (
   vrc_display_name VARCHAR(64)  NOT NULL,
   vrc_avatar_url   VARCHAR(512),
   note             VARCHAR(255) NOT NULL DEFAULT '',
+  -- NULL なら無期限。過ぎたらスイーパーが外す
+  expires_at       TIMESTAMPTZ,
+  -- 論理削除。NULL 以外の行はどのクエリからも見えない。保持期間を過ぎたら物理削除する
+  deleted_at       TIMESTAMPTZ,
+  -- VRChat アカウントの本人確認。NULL の間はワールド向けエクスポートに出さない
+  verified_at      TIMESTAMPTZ,
+  -- 本人確認用のワンタイムコード（bio に書いてもらう）と、その期限。過ぎたらスイーパーが外す
+  verify_code      VARCHAR(16)  NOT NULL DEFAULT '',
+  verify_until     TIMESTAMPTZ,
   created_at       TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP,
   updated_at       TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Discord ユーザーごとに1行（論理削除された行も含む。再登録・復元はこの行を生き返らせる）
 CREATE UNIQUE INDEX uq_discord_user ON whitelist_users (discord_user_id);
-CREATE UNIQUE INDEX uq_vrc_user     ON whitelist_users (vrc_user_id);
+-- VRChat アカウントを押さえるのは本人確認済みの行だけ（確認待ちは何人いてもよく、先に確認した人が取る）。
+-- 削除済みの行も握ったままにならないよう、生きている行だけで一意
+CREATE UNIQUE INDEX uq_vrc_user     ON whitelist_users (vrc_user_id) WHERE deleted_at IS NULL AND verified_at IS NOT NULL;
+CREATE INDEX idx_whitelist_users_vrc_user_id ON whitelist_users (vrc_user_id) WHERE deleted_at IS NULL;
+CREATE INDEX idx_whitelist_users_expires_at ON whitelist_users (expires_at);
+CREATE INDEX idx_whitelist_users_deleted_at ON whitelist_users (deleted_at);
+CREATE INDEX idx_whitelist_users_verify_until ON whitelist_users (verify_until);
+
+-- ホワイトリストの変更カウンタ（1行のみ）
+-- whitelist_users が変わるたびに version を +1 してエクスポートの ETag に使う
+CREATE TABLE whitelist_version (
+  id         SMALLINT     PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+  version    BIGINT       NOT NULL DEFAULT 0,
+  updated_at TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- ワールドごとのエクスポート用トークン
+-- token はハッシュだけ保存し、secret は署名(HMAC)に使うのでそのまま保存する
+CREATE TABLE world_export_tokens (
+  id           BIGSERIAL    PRIMARY KEY,
+  world_name   VARCHAR(128) NOT NULL,
+  token_hash   CHAR(64)     NOT NULL,
+  token_prefix VARCHAR(16)  NOT NULL,
+  secret       VARCHAR(128) NOT NULL,
+  created_at   TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  last_used_at TIMESTAMPTZ,
+  revoked_at   TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX uq_world_export_token_hash ON world_export_tokens (token_hash);
+
+-- 名前付きリスト（performers / judges / staff / vip など）
+-- self_service が true のリストだけ、本人が /whitelist パネルから参加・脱退できる
+CREATE TABLE whitelist_lists (
+  id           BIGSERIAL    PRIMARY KEY,
+  name         VARCHAR(64)  NOT NULL,
+  description  VARCHAR(255) NOT NULL DEFAULT '',
+  self_service BOOLEAN      NOT NULL DEFAULT false,
+  -- /whitelist パネルから参加できる期間。NULL の側は制限なし
+  registration_opens_at  TIMESTAMPTZ,
+  registration_closes_at TIMESTAMPTZ,
+  created_at   TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX uq_whitelist_list_name ON whitelist_lists (name);
+
+-- リストの所属。whitelist_users の紐付けを参照する
+CREATE TABLE whitelist_list_members (
+  list_id           BIGINT      NOT NULL REFERENCES whitelist_lists (id) ON DELETE CASCADE,
+  whitelist_user_id BIGINT      NOT NULL REFERENCES whitelist_users (id) ON DELETE CASCADE,
+  created_at        TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (list_id, whitelist_user_id)
+);
+
+CREATE INDEX idx_whitelist_list_members_user ON whitelist_list_members (whitelist_user_id);
+
+-- ホワイトリスト変更の監査ログ（追記のみ。UPDATE / DELETE はしない）
+-- before_value / after_value は変更前後の紐付けのスナップショット(JSON)
+CREATE TABLE whitelist_audit (
+  id              BIGSERIAL    PRIMARY KEY,
+  action          VARCHAR(32)  NOT NULL,
+  discord_user_id VARCHAR(64)  NOT NULL,
+  actor           VARCHAR(128) NOT NULL DEFAULT '',
+  source          VARCHAR(32)  NOT NULL,
+  before_value    JSONB,
+  after_value     JSONB,
+  request_id      VARCHAR(64)  NOT NULL DEFAULT '',
+  created_at      TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_whitelist_audit_discord_user ON whitelist_audit (discord_user_id, id);
+
+-- VRChat 表示名の変更履歴。定期再同期で名前が変わっていたときに追記する
+-- 紐付けが物理削除されても履歴は残したいので whitelist_users は参照しない
+CREATE TABLE whitelist_name_history (
+  id               BIGSERIAL   PRIMARY KEY,
+  discord_user_id  VARCHAR(64) NOT NULL,
+  vrc_user_id      VARCHAR(64) NOT NULL,
+  old_display_name VARCHAR(64) NOT NULL,
+  new_display_name VARCHAR(64) NOT NULL,
+  changed_at       TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_whitelist_name_history_discord_user ON whitelist_name_history (discord_user_id, id);
+
+-- 登録禁止リスト。Discord ID か VRChat user ID（どちらか一方でもよい）に一致すると登録できない
+-- added_by は追加した管理者（監査ログの actor と同じ値）
+CREATE TABLE whitelist_denylist (
+  id              BIGSERIAL    PRIMARY KEY,
+  discord_user_id VARCHAR(64),
+  vrc_user_id     VARCHAR(64),
+  reason          VARCHAR(255) NOT NULL DEFAULT '',
+  added_by        VARCHAR(128) NOT NULL DEFAULT '',
+  created_at      TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  CONSTRAINT chk_denylist_target CHECK (discord_user_id IS NOT NULL OR vrc_user_id IS NOT NULL)
+);
+
+CREATE UNIQUE INDEX uq_denylist_discord_user ON whitelist_denylist (discord_user_id);
+CREATE UNIQUE INDEX uq_denylist_vrc_user     ON whitelist_denylist (vrc_user_id);
+
+-- 本人による登録の受付期間（1行のみ）。NULL の側は制限なし
+CREATE TABLE whitelist_registration_window (
+  id         SMALLINT    PRIMARY KEY DEFAULT 1 CHECK (id = 1),
+  opens_at   TIMESTAMPTZ,
+  closes_at  TIMESTAMPTZ,
+  updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 受付期間の外でも登録・リスト参加できる人（管理者が個別に許可する）
+CREATE TABLE whitelist_registration_overrides (
+  discord_user_id VARCHAR(64)  PRIMARY KEY,
+  granted_by      VARCHAR(128) NOT NULL DEFAULT '',
+  created_at      TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- 定員（WHITELIST_CAPACITY）を超えた本人による登録のキャンセル待ち。id 順（並んだ順）に繰り上げる
+-- 並んだあとに VRChat アカウントを変えても順番はそのまま
+-- 同じ VRChat アカウントで何人並んでもよい。繰り上げのときに本人確認済みの紐付けとだけ突き合わせる
+CREATE TABLE whitelist_waitlist (
+  id               BIGSERIAL    PRIMARY KEY,
+  discord_user_id  VARCHAR(64)  NOT NULL,
+  vrc_user_id      VARCHAR(64)  NOT NULL,
+  vrc_display_name VARCHAR(64)  NOT NULL,
+  vrc_avatar_url   VARCHAR(512),
+  created_at       TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at       TIMESTAMPTZ  NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX uq_waitlist_discord_user ON whitelist_waitlist (discord_user_id);
+
+-- 本人による登録・変更の回数制限（VRChat API を叩き過ぎないように）。再起動しても残すため DB に持つ
+-- changes_since から 24時間のあいだに VRChat アカウントを変えた回数が change_count
+CREATE TABLE whitelist_register_limits (
+  discord_user_id VARCHAR(64) PRIMARY KEY,
+  last_attempt_at TIMESTAMPTZ NOT NULL,
+  changes_since   TIMESTAMPTZ NOT NULL,
+  change_count    INTEGER     NOT NULL DEFAULT 0
+);